@@ -0,0 +1,294 @@
+// Package simulate implements the simulate command, which runs a GitHub
+// webhook payload through the decision pipeline without changing anything in
+// the on-call backend.
+package simulate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	microflag "github.com/giantswarm/microkit/flag"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/giantswarm/auto-oncall/command/simulate/flag"
+	projectflag "github.com/giantswarm/auto-oncall/flag"
+	"github.com/giantswarm/auto-oncall/service"
+	"github.com/giantswarm/auto-oncall/service/dryrun"
+	"github.com/giantswarm/auto-oncall/service/tracing"
+	"github.com/giantswarm/auto-oncall/service/webhook"
+)
+
+const (
+	outputJSON = "json"
+	outputText = "text"
+
+	deliveryID = "simulated"
+)
+
+var (
+	f = flag.New()
+)
+
+// Config represents the configuration used to create a new simulate command.
+type Config struct {
+	Flag *projectflag.Flag
+
+	Description string
+	GitCommit   string
+	Name        string
+	Source      string
+	Viper       *viper.Viper
+}
+
+// Result is the output of the simulate command.
+type Result struct {
+	Decision webhook.Decision `json:"decision"`
+	Error    string           `json:"error,omitempty"`
+	Requests []dryrun.Request `json:"requests"`
+}
+
+// New creates a new simulate command.
+func New(config Config) (Command, error) {
+	if config.Flag == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Flag must not be empty", config)
+	}
+	if config.Viper == nil {
+		config.Viper = viper.New()
+	}
+
+	newCommand := &command{
+		cobraCommand: nil,
+
+		description: config.Description,
+		flag:        config.Flag,
+		gitCommit:   config.GitCommit,
+		name:        config.Name,
+		source:      config.Source,
+		viper:       config.Viper,
+	}
+
+	newCommand.cobraCommand = &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulate a GitHub webhook and show the resulting on-call decision.",
+		Long: "Simulate a GitHub webhook and show the resulting on-call decision. The payload is run through\n" +
+			"filtering, author resolution, user mapping and assignment construction, and the requests the\n" +
+			"provider would send are shown instead of being sent.",
+		Run: newCommand.Execute,
+	}
+
+	newCommand.cobraCommand.PersistentFlags().StringSlice(f.Config.Dirs, []string{"."}, "List of config file directories.")
+	newCommand.cobraCommand.PersistentFlags().StringSlice(f.Config.Files, []string{"config"}, "List of the config file names. All viper supported extensions can be used.")
+	newCommand.cobraCommand.PersistentFlags().String(f.Event, "deployment", "GitHub event type of the payload, as given in the X-GitHub-Event header.")
+	newCommand.cobraCommand.PersistentFlags().Bool(f.LiveReads, false, "Send GET requests, e.g. for resolving commit authors. All other requests are never sent.")
+	newCommand.cobraCommand.PersistentFlags().String(f.Output, outputText, "Output format, either text or json.")
+	newCommand.cobraCommand.PersistentFlags().String(f.Payload, "", "Path of the file containing the GitHub webhook payload.")
+
+	return newCommand, nil
+}
+
+type command struct {
+	// Internals.
+	cobraCommand *cobra.Command
+
+	// Settings.
+	description string
+	flag        *projectflag.Flag
+	gitCommit   string
+	name        string
+	source      string
+	viper       *viper.Viper
+}
+
+func (c *command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *command) Execute(cmd *cobra.Command, args []string) {
+	microflag.Parse(c.viper, cmd.Flags())
+
+	err := microflag.Merge(c.viper, cmd.Flags(), c.viper.GetStringSlice(f.Config.Dirs), c.viper.GetStringSlice(f.Config.Files))
+	if err != nil {
+		panic(err)
+	}
+
+	err = c.execute(os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+func (c *command) execute(w io.Writer) error {
+	output := c.viper.GetString(f.Output)
+	if output != outputJSON && output != outputText {
+		return microerror.Maskf(invalidFlagError, "--%s must be %#q or %#q", f.Output, outputJSON, outputText)
+	}
+	if c.viper.GetString(f.Payload) == "" {
+		return microerror.Maskf(invalidFlagError, "--%s must not be empty", f.Payload)
+	}
+
+	payload, err := ioutil.ReadFile(c.viper.GetString(f.Payload))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	hook, err := webhook.NewHookFromPayload(deliveryID, c.viper.GetString(f.Event), payload)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Nothing the daemon would persist is touched. The Alertmanager
+	// configuration is rendered into a temporary directory, assignments and
+	// the audit log are not stored and the webhook secret is not needed for
+	// payloads read from a file. Requests are recorded by the simulation
+	// itself, so dry-run mode is not needed either. Traces are not exported,
+	// as the command exits before they would be.
+	dir, err := ioutil.TempDir("", "auto-oncall-simulate")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c.viper.Set(c.flag.Service.Alertmanager.Config.Output, filepath.Join(dir, "alertmanager.yml"))
+	c.viper.Set(c.flag.Service.Audit.Path, "")
+	c.viper.Set(c.flag.Service.Oncall.DryRun, false)
+	c.viper.Set(c.flag.Service.State.Path, "")
+	c.viper.Set(c.flag.Service.Tracing.Exporter, tracing.ExporterNone)
+	// Without organizations the service needs a webhook secret, although
+	// simulated webhooks are not verified. With organizations it would make
+	// hooks of other organizations be handled, unlike by the daemon.
+	if c.viper.GetString(c.flag.Service.Oncall.WebhookSecret) == "" && len(c.viper.GetStringSlice(c.flag.Service.Oncall.Organizations)) == 0 {
+		c.viper.Set(c.flag.Service.Oncall.WebhookSecret, deliveryID)
+	}
+
+	var transport *dryrun.Transport
+	{
+		config := dryrun.Config{
+			LiveReads: c.viper.GetBool(f.LiveReads),
+		}
+
+		transport, err = dryrun.New(config)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	// Logs go to stderr so that the output can be processed.
+	var logger micrologger.Logger
+	{
+		config := micrologger.Config{
+			IOWriter: os.Stderr,
+		}
+
+		logger, err = micrologger.New(config)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var newService *service.Service
+	{
+		config := service.Config{
+			HttpClient: &http.Client{Timeout: time.Second * 10, Transport: transport},
+			Logger:     logger,
+
+			Description: c.description,
+			Flag:        c.flag,
+			GitCommit:   c.gitCommit,
+			Name:        c.name,
+			Source:      c.source,
+			Viper:       c.viper,
+		}
+
+		newService, err = service.New(config)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var result Result
+	{
+		result.Decision, err = newService.Webhook.Simulate(context.Background(), hook)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.Requests = transport.Requests()
+	}
+
+	if output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(result)
+	} else {
+		err = writeText(w, result)
+	}
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func writeText(w io.Writer, r Result) error {
+	d := r.Decision
+
+	switch {
+	case d.Skipped:
+		fmt.Fprintf(w, "Decision:     skipped, %s\n", d.Reason)
+	case d.Assignment != nil:
+		fmt.Fprintf(w, "Decision:     assign\n")
+	default:
+		fmt.Fprintf(w, "Decision:     none\n")
+	}
+	if d.GithubLogin != "" {
+		fmt.Fprintf(w, "GitHub login: %s\n", d.GithubLogin)
+	}
+	if d.User != "" {
+		fmt.Fprintf(w, "User:         %s\n", d.User)
+	}
+	if a := d.Assignment; a != nil {
+		fmt.Fprintf(w, "Assignment:   %s\n", a.Name)
+		fmt.Fprintf(w, "Repository:   %s\n", a.Repository)
+		fmt.Fprintf(w, "Ref:          %s\n", a.Ref)
+		fmt.Fprintf(w, "Environment:  %s\n", a.Environment)
+		fmt.Fprintf(w, "Expiry:       %s\n", a.Expiry.Format(time.RFC3339))
+	}
+	if t := d.TTL; t != nil {
+		fmt.Fprintf(w, "TTL:          %s, %s\n", t.TTL, t.Reason)
+	}
+	for _, p := range d.Policy {
+		fmt.Fprintf(w, "Policy:       %s, %s\n", p.Action, p.Reason)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(w, "Error:        %s\n", r.Error)
+	}
+
+	fmt.Fprintf(w, "\nRequests (%d):\n", len(r.Requests))
+	for _, req := range r.Requests {
+		sent := "not sent"
+		if req.Sent {
+			sent = "sent"
+		}
+		fmt.Fprintf(w, "\n%s %s (%s)\n", req.Method, req.URL, sent)
+
+		if req.Body != nil {
+			b, err := json.MarshalIndent(req.Body, "", "  ")
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			fmt.Fprintf(w, "%s\n", b)
+		}
+	}
+
+	return nil
+}
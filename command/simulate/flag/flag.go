@@ -0,0 +1,20 @@
+package flag
+
+import (
+	"github.com/giantswarm/microkit/command/daemon/flag/config"
+	"github.com/giantswarm/microkit/flag"
+)
+
+type Flag struct {
+	Config    config.Config
+	Event     string
+	LiveReads string
+	Output    string
+	Payload   string
+}
+
+func New() Flag {
+	f := Flag{}
+	flag.Init(&f)
+	return f
+}
@@ -0,0 +1,13 @@
+package simulate
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// Command represents the simulate command.
+type Command interface {
+	// CobraCommand returns the actual cobra command for the simulate command.
+	CobraCommand() *cobra.Command
+	// Execute represents the cobra run method.
+	Execute(cmd *cobra.Command, args []string)
+}
@@ -0,0 +1,11 @@
+package alertmanager
+
+type Alertmanager struct {
+	Config    Config `yaml:"config"`
+	ReloadURL string `yaml:"reloadURL"`
+}
+
+type Config struct {
+	Base   string `yaml:"base"`
+	Output string `yaml:"output"`
+}
@@ -0,0 +1,5 @@
+package audit
+
+type Audit struct {
+	Path string `yaml:"path"`
+}
@@ -0,0 +1,18 @@
+package auth
+
+type Auth struct {
+	OIDC   OIDC   `yaml:"oidc"`
+	Tokens string `yaml:"tokens"`
+}
+
+type OIDC struct {
+	Audience   string `yaml:"audience"`
+	Issuer     string `yaml:"issuer"`
+	JWKS       JWKS   `yaml:"jwks"`
+	RolesClaim string `yaml:"rolesClaim"`
+}
+
+type JWKS struct {
+	File string `yaml:"file"`
+	URL  string `yaml:"url"`
+}
@@ -0,0 +1,6 @@
+package directory
+
+type Directory struct {
+	Teams string `yaml:"teams"`
+	Users string `yaml:"users"`
+}
@@ -0,0 +1,10 @@
+package github
+
+type Github struct {
+	Announce Announce `yaml:"announce"`
+}
+
+type Announce struct {
+	Mode     string `yaml:"mode"`
+	Template string `yaml:"template"`
+}
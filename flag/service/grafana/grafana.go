@@ -0,0 +1,10 @@
+package grafana
+
+type Grafana struct {
+	Integration string `yaml:"integration"`
+	Mode        string `yaml:"mode"`
+	Schedule    string `yaml:"schedule"`
+	Team        string `yaml:"team"`
+	Token       string `yaml:"token"`
+	URL         string `yaml:"url"`
+}
@@ -0,0 +1,5 @@
+package health
+
+type Health struct {
+	CacheTTL string `yaml:"cacheTTL"`
+}
@@ -0,0 +1,5 @@
+package notifier
+
+type Notifier struct {
+	Webhooks string `yaml:"webhooks"`
+}
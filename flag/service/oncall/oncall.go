@@ -1,8 +1,17 @@
 package oncall
 
 type Oncall struct {
-	GithubToken   string `yaml:"githubToken"`
-	OpsgenieToken string `yaml:"opsgenieToken"`
-	Users         string `yaml:"users"`
-	WebhookSecret string `yaml:"webhookSecret"`
+	DryRun            string `yaml:"dryRun"`
+	ExternalURL       string `yaml:"externalURL"`
+	GithubToken       string `yaml:"githubToken"`
+	GithubTokenFile   string `yaml:"githubTokenFile"`
+	Handover          string `yaml:"handover"`
+	OpsgenieToken     string `yaml:"opsgenieToken"`
+	OpsgenieTokenFile string `yaml:"opsgenieTokenFile"`
+	Organizations     string `yaml:"organizations"`
+	Provider          string `yaml:"provider"`
+	Users             string `yaml:"users"`
+	WebhookSecret     string `yaml:"webhookSecret"`
+	WebhookSecretFile string `yaml:"webhookSecretFile"`
+	WebhookSecrets    string `yaml:"webhookSecrets"`
 }
@@ -0,0 +1,19 @@
+package opsgenie
+
+type Opsgenie struct {
+	Escalation  Escalation  `yaml:"escalation"`
+	Mode        string      `yaml:"mode"`
+	RoutingRule RoutingRule `yaml:"routingRule"`
+	Schedule    string      `yaml:"schedule"`
+	Team        string      `yaml:"team"`
+}
+
+type Escalation struct {
+	Policies  string `yaml:"policies"`
+	Selectors string `yaml:"selectors"`
+}
+
+type RoutingRule struct {
+	Conditions string `yaml:"conditions"`
+	Order      string `yaml:"order"`
+}
@@ -0,0 +1,12 @@
+package policy
+
+type Policy struct {
+	Availability        Availability `yaml:"availability"`
+	OutsideWorkingHours string       `yaml:"outsideWorkingHours"`
+	Schedule            string       `yaml:"schedule"`
+}
+
+type Availability struct {
+	Opsgenie        string `yaml:"opsgenie"`
+	RefreshInterval string `yaml:"refreshInterval"`
+}
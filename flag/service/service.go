@@ -1,9 +1,37 @@
 package service
 
 import (
+	"github.com/giantswarm/auto-oncall/flag/service/alertmanager"
+	"github.com/giantswarm/auto-oncall/flag/service/audit"
+	"github.com/giantswarm/auto-oncall/flag/service/auth"
+	"github.com/giantswarm/auto-oncall/flag/service/directory"
+	"github.com/giantswarm/auto-oncall/flag/service/github"
+	"github.com/giantswarm/auto-oncall/flag/service/grafana"
+	"github.com/giantswarm/auto-oncall/flag/service/health"
+	"github.com/giantswarm/auto-oncall/flag/service/notifier"
 	"github.com/giantswarm/auto-oncall/flag/service/oncall"
+	"github.com/giantswarm/auto-oncall/flag/service/opsgenie"
+	"github.com/giantswarm/auto-oncall/flag/service/policy"
+	"github.com/giantswarm/auto-oncall/flag/service/slack"
+	"github.com/giantswarm/auto-oncall/flag/service/state"
+	"github.com/giantswarm/auto-oncall/flag/service/tracing"
+	"github.com/giantswarm/auto-oncall/flag/service/ttl"
 )
 
 type Service struct {
-	Oncall oncall.Oncall
+	Alertmanager alertmanager.Alertmanager
+	Audit        audit.Audit
+	Auth         auth.Auth
+	Directory    directory.Directory
+	Github       github.Github
+	Grafana      grafana.Grafana
+	Health       health.Health
+	Notifier     notifier.Notifier
+	Oncall       oncall.Oncall
+	Opsgenie     opsgenie.Opsgenie
+	Policy       policy.Policy
+	Slack        slack.Slack
+	State        state.State
+	Tracing      tracing.Tracing
+	TTL          ttl.TTL
 }
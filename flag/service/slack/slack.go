@@ -0,0 +1,9 @@
+package slack
+
+type Slack struct {
+	APIURL     string `yaml:"apiURL"`
+	Channel    string `yaml:"channel"`
+	Templates  string `yaml:"templates"`
+	Token      string `yaml:"token"`
+	WebhookURL string `yaml:"webhookURL"`
+}
@@ -0,0 +1,6 @@
+package state
+
+type State struct {
+	Path              string `yaml:"path"`
+	ReconcileInterval string `yaml:"reconcileInterval"`
+}
@@ -0,0 +1,7 @@
+package tracing
+
+type Tracing struct {
+	Endpoint string `yaml:"endpoint"`
+	Exporter string `yaml:"exporter"`
+	Path     string `yaml:"path"`
+}
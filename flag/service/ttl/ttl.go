@@ -0,0 +1,6 @@
+package ttl
+
+type TTL struct {
+	Default string `yaml:"default"`
+	Rules   string `yaml:"rules"`
+}
@@ -2,12 +2,15 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/giantswarm/microkit/command"
 	microserver "github.com/giantswarm/microkit/server"
 	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/giantswarm/auto-oncall/command/simulate"
 	"github.com/giantswarm/auto-oncall/flag"
 	"github.com/giantswarm/auto-oncall/server"
 	"github.com/giantswarm/auto-oncall/service"
@@ -56,6 +59,8 @@ func main() {
 			if err != nil {
 				panic(fmt.Sprintf("%#v", err))
 			}
+
+			go newService.Boot()
 		}
 
 		var newServer microserver.Server
@@ -98,12 +103,88 @@ func main() {
 		}
 	}
 
-	daemonCommand := newCommand.DaemonCommand().CobraCommand()
+	var simulateCommand simulate.Command
+	{
+		c := simulate.Config{
+			Flag: f,
+
+			Description: description,
+			GitCommit:   gitCommit,
+			Name:        name,
+			Source:      source,
+			Viper:       viper.New(),
+		}
 
-	daemonCommand.PersistentFlags().String(f.Service.Oncall.GithubToken, "", "GitHub API token.")
-	daemonCommand.PersistentFlags().String(f.Service.Oncall.OpsgenieToken, "", "Opsgenie API token.")
-	daemonCommand.PersistentFlags().String(f.Service.Oncall.Users, "", "github_id:opsgenie_id mapppings, separated by comma.")
-	daemonCommand.PersistentFlags().String(f.Service.Oncall.WebhookSecret, "", "Github organization webhook secret.")
+		simulateCommand, err = simulate.New(c)
+		if err != nil {
+			panic(fmt.Sprintf("%#v", err))
+		}
+
+		newCommand.CobraCommand().AddCommand(simulateCommand.CobraCommand())
+	}
+
+	// The service flags are shared by the daemon and simulate commands, so
+	// that both can be run with the same configuration.
+	for _, cmd := range []*cobra.Command{newCommand.DaemonCommand().CobraCommand(), simulateCommand.CobraCommand()} {
+		cmd.PersistentFlags().String(f.Service.Alertmanager.Config.Base, "", "Path of the Alertmanager configuration managed routes are added to.")
+		cmd.PersistentFlags().String(f.Service.Alertmanager.Config.Output, "", "Path the Alertmanager configuration including managed routes is written to.")
+		cmd.PersistentFlags().String(f.Service.Alertmanager.ReloadURL, "", "Alertmanager reload endpoint called after the configuration has been written.")
+		cmd.PersistentFlags().String(f.Service.Audit.Path, "", "Path of the file the audit log is appended to. It is written to stdout when empty.")
+		cmd.PersistentFlags().String(f.Service.Auth.OIDC.Audience, "", "Audience required in OIDC tokens.")
+		cmd.PersistentFlags().String(f.Service.Auth.OIDC.Issuer, "", "Issuer required in OIDC tokens.")
+		cmd.PersistentFlags().String(f.Service.Auth.OIDC.JWKS.File, "", "Path of the JSON web key set OIDC tokens are verified with.")
+		cmd.PersistentFlags().String(f.Service.Auth.OIDC.JWKS.URL, "", "URL of the JSON web key set OIDC tokens are verified with.")
+		cmd.PersistentFlags().String(f.Service.Auth.OIDC.RolesClaim, "roles", "Claim of OIDC tokens listing the roles of the subject.")
+		cmd.PersistentFlags().String(f.Service.Auth.Tokens, "", "Static bearer tokens with name and role, configured as list in the secret file.")
+		cmd.PersistentFlags().String(f.Service.Directory.Teams, "", "Teams of the user directory with their absence calendars and on-call schedules, configured as list in the config file.")
+		cmd.PersistentFlags().String(f.Service.Directory.Users, "", "User directory with the Slack member IDs and working hours of deployers, configured as list in the config file.")
+		cmd.PersistentFlags().String(f.Service.Github.Announce.Mode, "none", "How assignments are announced on GitHub, either none, comment on the deployed commit or status of the deployment.")
+		cmd.PersistentFlags().String(f.Service.Github.Announce.Template, "", "Go template assignment announcements are rendered from. A built-in template is used when empty.")
+		cmd.PersistentFlags().String(f.Service.Grafana.Integration, "", "Grafana OnCall integration ID routes are created on in route mode.")
+		cmd.PersistentFlags().String(f.Service.Grafana.Mode, "override", "Grafana OnCall provider mode, either override or route.")
+		cmd.PersistentFlags().String(f.Service.Grafana.Schedule, "", "Grafana OnCall schedule ID overrides are created on in override mode.")
+		cmd.PersistentFlags().String(f.Service.Grafana.Team, "", "Grafana OnCall team ID owning created objects.")
+		cmd.PersistentFlags().String(f.Service.Grafana.Token, "", "Grafana OnCall API token.")
+		cmd.PersistentFlags().String(f.Service.Grafana.URL, "", "Grafana OnCall API base URL.")
+		cmd.PersistentFlags().String(f.Service.Notifier.Webhooks, "", "Outgoing webhooks changes of assignments are posted to, configured as list in the config file.")
+		cmd.PersistentFlags().Bool(f.Service.Oncall.DryRun, false, "Record and log the changes the provider would make instead of making them.")
+		cmd.PersistentFlags().Duration(f.Service.Health.CacheTTL, 30*time.Second, "Duration results of health checks are reused for.")
+		cmd.PersistentFlags().String(f.Service.Oncall.ExternalURL, "", "URL auto-oncall is reachable at, used for links to assignments.")
+		cmd.PersistentFlags().String(f.Service.Oncall.GithubToken, "", "GitHub API token.")
+		cmd.PersistentFlags().String(f.Service.Oncall.GithubTokenFile, "", "Path of the file the GitHub API token is read from instead, read again when changed.")
+		cmd.PersistentFlags().String(f.Service.Oncall.Handover, "replace", "Handover mode when a repository is deployed to an environment with an active assignment, either replace, share or keep.")
+		cmd.PersistentFlags().String(f.Service.Oncall.Organizations, "", "GitHub organizations with their own webhook secret, GitHub token, repositories, Opsgenie team and user mapping, configured as list in the secret file.")
+		cmd.PersistentFlags().String(f.Service.Oncall.OpsgenieToken, "", "Opsgenie API token.")
+		cmd.PersistentFlags().String(f.Service.Oncall.OpsgenieTokenFile, "", "Path of the file the Opsgenie API token is read from instead, read again when changed.")
+		cmd.PersistentFlags().String(f.Service.Oncall.Provider, "opsgenie", "On-call provider, either opsgenie, grafana or alertmanager.")
+		cmd.PersistentFlags().String(f.Service.Opsgenie.Escalation.Policies, "", "Opsgenie escalation policies, configured as list in the config file.")
+		cmd.PersistentFlags().String(f.Service.Opsgenie.Escalation.Selectors, "", "Opsgenie escalation policy selectors by repository and environment, configured as list in the config file.")
+		cmd.PersistentFlags().String(f.Service.Opsgenie.Mode, "routingrule", "Opsgenie provider mode, either routingrule or override.")
+		cmd.PersistentFlags().String(f.Service.Opsgenie.RoutingRule.Conditions, "", "Opsgenie routing rule conditions, configured as list in the config file.")
+		cmd.PersistentFlags().Int(f.Service.Opsgenie.RoutingRule.Order, 0, "Opsgenie routing rule order within the team.")
+		cmd.PersistentFlags().String(f.Service.Opsgenie.Schedule, "", "Opsgenie schedule name overrides are created on in override mode.")
+		cmd.PersistentFlags().String(f.Service.Opsgenie.Team, "ops_team", "Opsgenie team owning escalations and routing rules.")
+		cmd.PersistentFlags().Bool(f.Service.Policy.Availability.Opsgenie, false, "Consider deployers forwarding their notifications or overridden on the schedule of their team in Opsgenie absent.")
+		cmd.PersistentFlags().Duration(f.Service.Policy.Availability.RefreshInterval, 15*time.Minute, "Interval absence calendars are fetched again in.")
+		cmd.PersistentFlags().String(f.Service.Policy.OutsideWorkingHours, "none", "Action on assignments reaching outside the working hours of the deployer, either none, skip, shorten or coresponder.")
+		cmd.PersistentFlags().String(f.Service.Policy.Schedule, "", "Regular on-call schedule paged together with deployers outside their working hours with the coresponder policy.")
+		cmd.PersistentFlags().String(f.Service.Slack.APIURL, "https://slack.com/api", "Slack Web API base URL.")
+		cmd.PersistentFlags().String(f.Service.Slack.Channel, "", "Slack channel ID or name summaries are posted to with the Slack token.")
+		cmd.PersistentFlags().String(f.Service.Slack.Templates, "", "Slack message templates by recipient and event, configured as map in the config file.")
+		cmd.PersistentFlags().String(f.Service.Slack.Token, "", "Slack bot token used for direct messages and channel summaries.")
+		cmd.PersistentFlags().String(f.Service.Slack.WebhookURL, "", "Slack incoming webhook URL summaries are posted to instead of the channel.")
+		cmd.PersistentFlags().String(f.Service.State.Path, "", "Path of the file assignments are stored in. Assignments are kept in memory only when empty.")
+		cmd.PersistentFlags().Duration(f.Service.State.ReconcileInterval, 5*time.Minute, "Interval the provider is reconciled with stored assignments in.")
+		cmd.PersistentFlags().String(f.Service.Tracing.Endpoint, "", "Base URL of the OTLP/HTTP receiver traces are sent to with the otlp exporter, e.g. http://localhost:4318.")
+		cmd.PersistentFlags().String(f.Service.Tracing.Exporter, "none", "Exporter of traces of webhook processing, either none, otlp, stdout or file.")
+		cmd.PersistentFlags().String(f.Service.Tracing.Path, "", "Path of the file traces are appended to with the file exporter.")
+		cmd.PersistentFlags().Duration(f.Service.TTL.Default, time.Hour, "Duration deployers stay on call for deployments without matching TTL rule.")
+		cmd.PersistentFlags().String(f.Service.TTL.Rules, "", "TTL rules by repository and environment, configured as list in the config file.")
+		cmd.PersistentFlags().String(f.Service.Oncall.Users, "", "github_id:opsgenie_id mapppings, separated by comma.")
+		cmd.PersistentFlags().String(f.Service.Oncall.WebhookSecret, "", "Github organization webhook secret.")
+		cmd.PersistentFlags().String(f.Service.Oncall.WebhookSecretFile, "", "Path of the file the Github organization webhook secret is read from instead, read again when changed.")
+		cmd.PersistentFlags().String(f.Service.Oncall.WebhookSecrets, "", "Github organization webhook secrets accepted after the webhook secret, tried in order, configured as list in the secret file.")
+	}
 
 	newCommand.CobraCommand().Execute()
 }
@@ -0,0 +1,128 @@
+package creator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/giantswarm/auto-oncall/server/middleware"
+	"github.com/giantswarm/auto-oncall/service"
+)
+
+const (
+	// Method is the HTTP method this endpoint is registered for.
+	Method = "POST"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "assignment/creator"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/assignments"
+)
+
+// Config represents the configuration used to create a creator endpoint.
+type Config struct {
+	// Dependencies.
+	Logger     micrologger.Logger
+	Middleware *middleware.Middleware
+	Service    *service.Service
+}
+
+// New creates a new configured creator endpoint.
+func New(config Config) (*Endpoint, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+	if config.Middleware == nil {
+		return nil, microerror.Maskf(invalidConfigError, "middleware must not be empty")
+	}
+	if config.Service == nil {
+		return nil, microerror.Maskf(invalidConfigError, "service must not be empty")
+	}
+
+	e := &Endpoint{
+		Config: config,
+	}
+
+	return e, nil
+}
+
+type Endpoint struct {
+	Config
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		return r, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		endpointResponse := response.(*Response)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(endpointResponse.StatusCode)
+		return json.NewEncoder(w).Encode(endpointResponse.Body)
+	}
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		r := request.(*http.Request)
+
+		response := DefaultResponse()
+
+		var body Request
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			response.Body.Message = err.Error()
+			response.StatusCode = http.StatusBadRequest
+			return response, nil
+		}
+		ttl, err := time.ParseDuration(body.TTL)
+		if err != nil {
+			response.Body.Message = err.Error()
+			response.StatusCode = http.StatusBadRequest
+			return response, nil
+		}
+
+		a, err := e.Service.Webhook.Assign(ctx, middleware.Actor(ctx), body.Organization, body.Repository, body.Environment, body.User, ttl)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		response.Body.Assignment = &a
+		response.Body.Message = "assignment created"
+		// With handover mode keep the previous deployer may stay on call,
+		// the requested assignment is pending or skipped then.
+		if a.GithubLogin != body.User {
+			response.Body.Message = "previous deployer stays on call"
+		}
+		response.StatusCode = http.StatusCreated
+
+		return response, nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return []kitendpoint.Middleware{
+		e.Middleware.Admin,
+	}
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
@@ -0,0 +1,36 @@
+package creator
+
+import (
+	"github.com/giantswarm/auto-oncall/service/assignment"
+)
+
+// Request is the body of requests creating an assignment. User is the GitHub
+// login of the engineer put on call, TTL a duration like 2h. Organization is
+// only given for repositories of explicitly configured organizations.
+type Request struct {
+	Environment  string `json:"environment"`
+	Organization string `json:"organization,omitempty"`
+	Repository   string `json:"repository"`
+	TTL          string `json:"ttl"`
+	User         string `json:"user"`
+}
+
+// Response is a struct that represents what this endpoint returns.
+type Response struct {
+	Body       Body
+	StatusCode int `json:"-"`
+}
+
+type Body struct {
+	Assignment *assignment.Assignment `json:"assignment,omitempty"`
+	Message    string                 `json:"message"`
+}
+
+// DefaultResponse returns empty Response.
+func DefaultResponse() *Response {
+	return &Response{
+		Body: Body{
+			Message: "",
+		},
+	}
+}
@@ -0,0 +1,31 @@
+package extender
+
+import (
+	"github.com/giantswarm/auto-oncall/service/assignment"
+)
+
+// Request is the body of requests extending an assignment. TTL is the
+// duration from now the assignment ends after, like 2h.
+type Request struct {
+	TTL string `json:"ttl"`
+}
+
+// Response is a struct that represents what this endpoint returns.
+type Response struct {
+	Body       Body
+	StatusCode int `json:"-"`
+}
+
+type Body struct {
+	Assignment *assignment.Assignment `json:"assignment,omitempty"`
+	Message    string                 `json:"message"`
+}
+
+// DefaultResponse returns empty Response.
+func DefaultResponse() *Response {
+	return &Response{
+		Body: Body{
+			Message: "",
+		},
+	}
+}
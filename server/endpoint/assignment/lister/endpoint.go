@@ -0,0 +1,111 @@
+package lister
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/giantswarm/auto-oncall/server/middleware"
+	"github.com/giantswarm/auto-oncall/service"
+	"github.com/giantswarm/auto-oncall/service/webhook"
+)
+
+const (
+	// Method is the HTTP method this endpoint is registered for.
+	Method = "GET"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "assignment/lister"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/assignments"
+)
+
+// Config represents the configuration used to create a lister endpoint.
+type Config struct {
+	// Dependencies.
+	Logger     micrologger.Logger
+	Middleware *middleware.Middleware
+	Service    *service.Service
+}
+
+// New creates a new configured lister endpoint.
+func New(config Config) (*Endpoint, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+	if config.Middleware == nil {
+		return nil, microerror.Maskf(invalidConfigError, "middleware must not be empty")
+	}
+	if config.Service == nil {
+		return nil, microerror.Maskf(invalidConfigError, "service must not be empty")
+	}
+
+	e := &Endpoint{
+		Config: config,
+	}
+
+	return e, nil
+}
+
+type Endpoint struct {
+	Config
+}
+
+// Decoder reads the optional filters repository, environment and user from
+// the query string. The user filter matches GitHub logins and mapped users.
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		query := r.URL.Query()
+
+		request := webhook.Filter{
+			Environment:  query.Get("environment"),
+			Organization: query.Get("organization"),
+			Repository:   query.Get("repository"),
+			User:         query.Get("user"),
+		}
+
+		return request, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		return json.NewEncoder(w).Encode(response)
+	}
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		filter := request.(webhook.Filter)
+
+		response := DefaultResponse()
+		response.Assignments = append(response.Assignments, e.Service.Webhook.Assignments(filter)...)
+
+		return response, nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return []kitendpoint.Middleware{
+		e.Middleware.ReadOnly,
+	}
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
@@ -0,0 +1,17 @@
+package lister
+
+import (
+	"github.com/giantswarm/auto-oncall/service/assignment"
+)
+
+// Response is the list of active assignments.
+type Response struct {
+	Assignments []assignment.Assignment `json:"assignments"`
+}
+
+// DefaultResponse returns an empty Response.
+func DefaultResponse() *Response {
+	return &Response{
+		Assignments: []assignment.Assignment{},
+	}
+}
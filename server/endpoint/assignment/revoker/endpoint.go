@@ -0,0 +1,108 @@
+package revoker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+
+	"github.com/giantswarm/auto-oncall/server/middleware"
+	"github.com/giantswarm/auto-oncall/service"
+)
+
+const (
+	// Method is the HTTP method this endpoint is registered for.
+	Method = "DELETE"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "assignment/revoker"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/assignments/{name}"
+)
+
+// Config represents the configuration used to create a revoker endpoint.
+type Config struct {
+	// Dependencies.
+	Logger     micrologger.Logger
+	Middleware *middleware.Middleware
+	Service    *service.Service
+}
+
+// New creates a new configured revoker endpoint.
+func New(config Config) (*Endpoint, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+	if config.Middleware == nil {
+		return nil, microerror.Maskf(invalidConfigError, "middleware must not be empty")
+	}
+	if config.Service == nil {
+		return nil, microerror.Maskf(invalidConfigError, "service must not be empty")
+	}
+
+	e := &Endpoint{
+		Config: config,
+	}
+
+	return e, nil
+}
+
+type Endpoint struct {
+	Config
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		return r, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		endpointResponse := response.(*Response)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(endpointResponse.StatusCode)
+		return json.NewEncoder(w).Encode(endpointResponse.Body)
+	}
+}
+
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		r := request.(*http.Request)
+
+		response := DefaultResponse()
+
+		err := e.Service.Webhook.Revoke(ctx, middleware.Actor(ctx), mux.Vars(r)["name"])
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		response.Body.Message = "assignment revoked"
+		response.StatusCode = http.StatusOK
+
+		return response, nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return []kitendpoint.Middleware{
+		e.Middleware.Admin,
+	}
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/giantswarm/auto-oncall/server/middleware"
+	"github.com/giantswarm/auto-oncall/service"
+	"github.com/giantswarm/auto-oncall/service/audit"
+)
+
+const (
+	// Method is the HTTP method this endpoint is registered for.
+	Method = "GET"
+	// Name identifies the endpoint. It is aligned to the package path.
+	Name = "audit"
+	// Path is the HTTP request path this endpoint is registered for.
+	Path = "/audit"
+)
+
+// Config represents the configuration used to create an audit endpoint.
+type Config struct {
+	// Dependencies.
+	Logger     micrologger.Logger
+	Middleware *middleware.Middleware
+	Service    *service.Service
+}
+
+// New creates a new configured audit endpoint.
+func New(config Config) (*Endpoint, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+	if config.Middleware == nil {
+		return nil, microerror.Maskf(invalidConfigError, "middleware must not be empty")
+	}
+	if config.Service == nil {
+		return nil, microerror.Maskf(invalidConfigError, "service must not be empty")
+	}
+
+	e := &Endpoint{
+		Config: config,
+	}
+
+	return e, nil
+}
+
+type Endpoint struct {
+	Config
+}
+
+func (e *Endpoint) Decoder() kithttp.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		return r, nil
+	}
+}
+
+func (e *Endpoint) Encoder() kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		endpointResponse := response.(*Response)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(endpointResponse.StatusCode)
+		return json.NewEncoder(w).Encode(endpointResponse.Body)
+	}
+}
+
+// Endpoint lists the entries of the audit log, optionally filtered by the
+// time range given as RFC 3339 timestamps in the from and to query
+// parameters and by the user query parameter.
+func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		query := request.(*http.Request).URL.Query()
+
+		response := DefaultResponse()
+
+		q := audit.Query{
+			User: query.Get("user"),
+		}
+		for param, t := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+			if query.Get(param) == "" {
+				continue
+			}
+
+			var err error
+			*t, err = time.Parse(time.RFC3339, query.Get(param))
+			if err != nil {
+				response.Body.Message = err.Error()
+				response.StatusCode = http.StatusBadRequest
+				return response, nil
+			}
+		}
+
+		entries, err := e.Service.Audit.Query(q)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		response.Body.Entries = append(response.Body.Entries, entries...)
+		response.StatusCode = http.StatusOK
+
+		return response, nil
+	}
+}
+
+func (e *Endpoint) Method() string {
+	return Method
+}
+
+func (e *Endpoint) Middlewares() []kitendpoint.Middleware {
+	return []kitendpoint.Middleware{
+		e.Middleware.Admin,
+	}
+}
+
+func (e *Endpoint) Name() string {
+	return Name
+}
+
+func (e *Endpoint) Path() string {
+	return Path
+}
@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"github.com/giantswarm/auto-oncall/service/audit"
+)
+
+// Response is a struct that represents what this endpoint returns.
+type Response struct {
+	Body       Body
+	StatusCode int `json:"-"`
+}
+
+type Body struct {
+	Entries []audit.Entry `json:"entries"`
+	Message string        `json:"message,omitempty"`
+}
+
+// DefaultResponse returns empty Response.
+func DefaultResponse() *Response {
+	return &Response{
+		Body: Body{
+			Entries: []audit.Entry{},
+		},
+	}
+}
@@ -0,0 +1,14 @@
+package cleanup
+
+// Response is the result of the cleanup.
+type Response struct {
+	// Deleted is the number of deleted expired assignments.
+	Deleted int `json:"deleted"`
+}
+
+// DefaultResponse returns an empty Response.
+func DefaultResponse() *Response {
+	return &Response{
+		Deleted: 0,
+	}
+}
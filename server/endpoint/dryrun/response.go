@@ -0,0 +1,21 @@
+package dryrun
+
+import (
+	"github.com/giantswarm/auto-oncall/service/dryrun"
+)
+
+// Response lists the changes recorded in dry-run mode.
+type Response struct {
+	// Enabled tells whether dry-run mode is enabled.
+	Enabled bool `json:"enabled"`
+	// Requests are the requests the provider would have sent, oldest first.
+	Requests []dryrun.Request `json:"requests"`
+}
+
+// DefaultResponse returns an empty Response.
+func DefaultResponse() *Response {
+	return &Response{
+		Enabled:  false,
+		Requests: []dryrun.Request{},
+	}
+}
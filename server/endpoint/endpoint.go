@@ -6,6 +6,15 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/giantswarm/auto-oncall/flag"
+	"github.com/giantswarm/auto-oncall/server/endpoint/assignment/creator"
+	"github.com/giantswarm/auto-oncall/server/endpoint/assignment/extender"
+	"github.com/giantswarm/auto-oncall/server/endpoint/assignment/lister"
+	"github.com/giantswarm/auto-oncall/server/endpoint/assignment/revoker"
+	"github.com/giantswarm/auto-oncall/server/endpoint/audit"
+	"github.com/giantswarm/auto-oncall/server/endpoint/cleanup"
+	"github.com/giantswarm/auto-oncall/server/endpoint/dryrun"
+	"github.com/giantswarm/auto-oncall/server/endpoint/healthz"
+	"github.com/giantswarm/auto-oncall/server/endpoint/readyz"
 	"github.com/giantswarm/auto-oncall/server/endpoint/version"
 	"github.com/giantswarm/auto-oncall/server/endpoint/webhook"
 	"github.com/giantswarm/auto-oncall/server/middleware"
@@ -24,8 +33,23 @@ type Config struct {
 
 // Endpoint is the endpoint collection.
 type Endpoint struct {
-	Version *version.Endpoint
-	Webhook *webhook.Endpoint
+	Assignment AssignmentEndpoint
+	Audit      *audit.Endpoint
+	Cleanup    *cleanup.Endpoint
+	DryRun     *dryrun.Endpoint
+	Healthz    *healthz.Endpoint
+	Readyz     *readyz.Endpoint
+	Version    *version.Endpoint
+	Webhook    *webhook.Endpoint
+}
+
+// AssignmentEndpoint is the collection of admin endpoints managing
+// assignments.
+type AssignmentEndpoint struct {
+	Creator  *creator.Endpoint
+	Extender *extender.Endpoint
+	Lister   *lister.Endpoint
+	Revoker  *revoker.Endpoint
 }
 
 // New creates a new configured endpoint.
@@ -39,6 +63,123 @@ func New(config Config) (*Endpoint, error) {
 
 	var err error
 
+	var creatorEndpoint *creator.Endpoint
+	{
+		c := creator.Config{
+			Logger:     config.Logger,
+			Middleware: config.Middleware,
+			Service:    config.Service,
+		}
+		creatorEndpoint, err = creator.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var extenderEndpoint *extender.Endpoint
+	{
+		c := extender.Config{
+			Logger:     config.Logger,
+			Middleware: config.Middleware,
+			Service:    config.Service,
+		}
+		extenderEndpoint, err = extender.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var listerEndpoint *lister.Endpoint
+	{
+		c := lister.Config{
+			Logger:     config.Logger,
+			Middleware: config.Middleware,
+			Service:    config.Service,
+		}
+		listerEndpoint, err = lister.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var revokerEndpoint *revoker.Endpoint
+	{
+		c := revoker.Config{
+			Logger:     config.Logger,
+			Middleware: config.Middleware,
+			Service:    config.Service,
+		}
+		revokerEndpoint, err = revoker.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var auditEndpoint *audit.Endpoint
+	{
+		c := audit.Config{
+			Logger:     config.Logger,
+			Middleware: config.Middleware,
+			Service:    config.Service,
+		}
+		auditEndpoint, err = audit.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var cleanupEndpoint *cleanup.Endpoint
+	{
+		c := cleanup.Config{
+			Logger:     config.Logger,
+			Middleware: config.Middleware,
+			Service:    config.Service,
+		}
+		cleanupEndpoint, err = cleanup.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var dryRunEndpoint *dryrun.Endpoint
+	{
+		c := dryrun.Config{
+			Logger:     config.Logger,
+			Middleware: config.Middleware,
+			Service:    config.Service,
+		}
+		dryRunEndpoint, err = dryrun.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var healthzEndpoint *healthz.Endpoint
+	{
+		c := healthz.Config{
+			Logger:     config.Logger,
+			Middleware: config.Middleware,
+			Service:    config.Service,
+		}
+		healthzEndpoint, err = healthz.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var readyzEndpoint *readyz.Endpoint
+	{
+		c := readyz.Config{
+			Logger:     config.Logger,
+			Middleware: config.Middleware,
+			Service:    config.Service,
+		}
+		readyzEndpoint, err = readyz.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var versionEndpoint *version.Endpoint
 	{
 		c := version.Config{
@@ -66,6 +207,17 @@ func New(config Config) (*Endpoint, error) {
 	}
 
 	e := &Endpoint{
+		Assignment: AssignmentEndpoint{
+			Creator:  creatorEndpoint,
+			Extender: extenderEndpoint,
+			Lister:   listerEndpoint,
+			Revoker:  revokerEndpoint,
+		},
+		Audit:   auditEndpoint,
+		Cleanup: cleanupEndpoint,
+		DryRun:  dryRunEndpoint,
+		Healthz: healthzEndpoint,
+		Readyz:  readyzEndpoint,
 		Version: versionEndpoint,
 		Webhook: webhookEndpoint,
 	}
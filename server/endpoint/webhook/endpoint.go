@@ -12,6 +12,7 @@ import (
 
 	"github.com/giantswarm/auto-oncall/server/middleware"
 	"github.com/giantswarm/auto-oncall/service"
+	"github.com/giantswarm/auto-oncall/service/tracing"
 )
 
 const (
@@ -21,6 +22,8 @@ const (
 	Name = "webhook"
 	// Path is the HTTP request path this endpoint is registered for.
 	Path = "/webhook"
+
+	deliveryHeader = "X-GitHub-Delivery"
 )
 
 // Config represents the configuration used to create a version endpoint.
@@ -77,16 +80,33 @@ func (e *Endpoint) Endpoint() kitendpoint.Endpoint {
 
 		response := DefaultResponse()
 
+		// The trace is keyed by the delivery ID, so that it can be found
+		// for a delivery listed by GitHub.
+		ctx, span := e.Service.Tracer.StartTrace(ctx, "webhook", r.Header.Get(deliveryHeader))
+		span.SetAttribute("delivery", r.Header.Get(deliveryHeader))
+		defer func() {
+			span.SetAttribute("http.status_code", response.StatusCode)
+			span.End(nil)
+		}()
+
+		_, intake := tracing.Start(ctx, "intake")
 		h, err := e.Service.Webhook.NewHook(r)
+		intake.End(err)
 		if err != nil {
 			response.Body.Message = err.Error()
 			response.StatusCode = http.StatusBadRequest
-		} else {
-			response.Body.Message = "webhook request received"
-			response.StatusCode = http.StatusOK
+			return response, nil
+		}
+
+		err = e.Service.Webhook.Enqueue(ctx, h)
+		if err != nil {
+			response.Body.Message = err.Error()
+			response.StatusCode = http.StatusServiceUnavailable
+			return response, nil
 		}
 
-		go e.Service.Webhook.Process(h)
+		response.Body.Message = "webhook request received"
+		response.StatusCode = http.StatusOK
 
 		return response, nil
 	}
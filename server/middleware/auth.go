@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+
+	"github.com/giantswarm/auto-oncall/service/audit"
+)
+
+const (
+	// RoleAdmin allows reading and changing assignments.
+	RoleAdmin = "admin"
+	// RoleReadOnly allows reading assignments.
+	RoleReadOnly = "readonly"
+)
+
+const (
+	bearerPrefix = "Bearer "
+)
+
+type contextKey int
+
+const (
+	actorKey contextKey = iota
+)
+
+// Token is a static bearer token. Name identifies the token holder in logs
+// and the audit log.
+type Token struct {
+	Name  string
+	Role  string
+	Token string
+}
+
+// identity is an authenticated caller.
+type identity struct {
+	name  string
+	roles []string
+}
+
+type authenticator struct {
+	audit  *audit.Service
+	logger micrologger.Logger
+
+	tokens   []Token
+	verifier *verifier
+}
+
+func newAuthenticator(config Config) (*authenticator, error) {
+	names := map[string]bool{}
+	for _, t := range config.Tokens {
+		if t.Name == "" || t.Token == "" {
+			return nil, microerror.Maskf(invalidConfigError, "tokens must have name and token")
+		}
+		if names[t.Name] {
+			return nil, microerror.Maskf(invalidConfigError, "token %#q must not be defined twice", t.Name)
+		}
+		if t.Role != RoleAdmin && t.Role != RoleReadOnly {
+			return nil, microerror.Maskf(invalidConfigError, "token %#q must have role %#q or %#q, got %#q", t.Name, RoleAdmin, RoleReadOnly, t.Role)
+		}
+		names[t.Name] = true
+	}
+
+	var v *verifier
+	if config.OIDC.JWKSFile != "" || config.OIDC.JWKSURL != "" {
+		var err error
+		v, err = newVerifier(config.OIDC)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	a := &authenticator{
+		audit:  config.Service.Audit,
+		logger: config.Logger,
+
+		tokens:   config.Tokens,
+		verifier: v,
+	}
+
+	return a, nil
+}
+
+// Actor returns the name of the authenticated caller of the request, if any.
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey).(string)
+	return actor
+}
+
+// require returns a middleware allowing requests authenticated with the given
+// role. The admin role includes the read-only role. Denied requests are
+// recorded in the audit log.
+func (a *authenticator) require(role string) kitendpoint.Middleware {
+	return func(next kitendpoint.Endpoint) kitendpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			header, _ := ctx.Value(kithttp.ContextKeyRequestAuthorization).(string)
+
+			id, err := a.authenticate(header)
+			if err != nil {
+				a.deny(ctx, id.name, err)
+				return nil, microerror.Mask(err)
+			}
+			if !hasRole(id.roles, role) {
+				err = microerror.Maskf(forbiddenError, "role %#q required", role)
+				a.deny(ctx, id.name, err)
+				return nil, microerror.Mask(err)
+			}
+
+			return next(context.WithValue(ctx, actorKey, id.name), request)
+		}
+	}
+}
+
+// authenticate returns the identity of the given Authorization header. Static
+// tokens are checked first, OIDC tokens afterwards.
+func (a *authenticator) authenticate(header string) (identity, error) {
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return identity{}, microerror.Maskf(unauthorizedError, "bearer token required")
+	}
+	token := strings.TrimPrefix(header, bearerPrefix)
+
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t.Token)) == 1 {
+			return identity{name: t.Name, roles: []string{t.Role}}, nil
+		}
+	}
+
+	if a.verifier == nil || strings.Count(token, ".") != 2 {
+		return identity{}, microerror.Maskf(unauthorizedError, "invalid bearer token")
+	}
+
+	id, err := a.verifier.verify(token)
+	if err != nil {
+		return id, microerror.Mask(err)
+	}
+
+	return id, nil
+}
+
+func (a *authenticator) deny(ctx context.Context, actor string, err error) {
+	method, _ := ctx.Value(kithttp.ContextKeyRequestMethod).(string)
+	path, _ := ctx.Value(kithttp.ContextKeyRequestPath).(string)
+	source, _ := ctx.Value(kithttp.ContextKeyRequestRemoteAddr).(string)
+
+	a.audit.Record(audit.Entry{
+		Origin:  audit.OriginAdmin,
+		Actor:   actor,
+		Action:  fmt.Sprintf("%s %s", method, path),
+		Outcome: audit.OutcomeDenied,
+		Reason:  err.Error(),
+		Source:  source,
+	})
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role || r == RoleAdmin && role == RoleReadOnly {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+	kitendpoint "github.com/go-kit/kit/endpoint"
 
 	"github.com/giantswarm/auto-oncall/service"
 )
@@ -11,15 +13,43 @@ type Config struct {
 	// Dependencies.
 	Logger  micrologger.Logger
 	Service *service.Service
+
+	// Settings.
+	// OIDC configures the validation of OIDC tokens. OIDC tokens are not
+	// accepted when neither a JWKS file nor a JWKS URL is configured.
+	OIDC OIDCConfig
+	// Tokens are the static bearer tokens accepted in addition to OIDC
+	// tokens.
+	Tokens []Token
 }
 
 // New creates a new configured middleware.
 func New(config Config) (*Middleware, error) {
-	newMiddleware := &Middleware{}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Service == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Service must not be empty", config)
+	}
+
+	a, err := newAuthenticator(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	newMiddleware := &Middleware{
+		Admin:    a.require(RoleAdmin),
+		ReadOnly: a.require(RoleReadOnly),
+	}
 
 	return newMiddleware, nil
 }
 
 // Middleware is middleware collection.
 type Middleware struct {
+	// Admin allows requests authenticated with the admin role only.
+	Admin kitendpoint.Middleware
+	// ReadOnly allows requests authenticated with the read-only or the admin
+	// role.
+	ReadOnly kitendpoint.Middleware
 }
@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// jwksMaxAge is the age after which the key set is fetched again.
+	jwksMaxAge = time.Hour
+	// jwksMinAge is the minimum age of the key set before it is fetched again
+	// for a token signed with an unknown key.
+	jwksMinAge = time.Minute
+
+	signingAlgorithm = "RS256"
+)
+
+// OIDCConfig configures the validation of OIDC tokens. Tokens must be signed
+// with RS256 by a key of the JSON web key set given as file or URL.
+type OIDCConfig struct {
+	// Audience, if set, must be an audience of tokens.
+	Audience string
+	// Issuer, if set, must be the issuer of tokens.
+	Issuer   string
+	JWKSFile string
+	JWKSURL  string
+	// RolesClaim is the claim listing the roles of the subject, e.g. roles.
+	RolesClaim string
+}
+
+// verifier validates OIDC tokens.
+type verifier struct {
+	config     OIDCConfig
+	httpClient *http.Client
+
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+	mutex   sync.Mutex
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	E   string `json:"e"`
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func newVerifier(config OIDCConfig) (*verifier, error) {
+	if config.JWKSFile != "" && config.JWKSURL != "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.JWKSFile and %T.JWKSURL must not both be set", config, config)
+	}
+	if config.RolesClaim == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.RolesClaim must not be empty", config)
+	}
+
+	v := &verifier{
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	// The key set is loaded once at startup, so configuration errors are
+	// noticed right away.
+	_, err := v.key("")
+	if err != nil && !IsUnauthorized(err) {
+		return nil, microerror.Mask(err)
+	}
+
+	return v, nil
+}
+
+// verify validates the signature and the claims of the given token and
+// returns the identity of its subject.
+func (v *verifier) verify(token string) (identity, error) {
+	parts := strings.Split(token, ".")
+
+	var header jwtHeader
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return identity{}, microerror.Maskf(unauthorizedError, "invalid token header")
+	}
+	if header.Alg != signingAlgorithm {
+		return identity{}, microerror.Maskf(unauthorizedError, "unsupported token algorithm %#q", header.Alg)
+	}
+
+	key, err := v.key(header.Kid)
+	if err != nil {
+		return identity{}, microerror.Mask(err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return identity{}, microerror.Maskf(unauthorizedError, "invalid token signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	if err != nil {
+		return identity{}, microerror.Maskf(unauthorizedError, "invalid token signature")
+	}
+
+	var claims map[string]interface{}
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return identity{}, microerror.Maskf(unauthorizedError, "invalid token claims")
+	}
+
+	subject, _ := claims["sub"].(string)
+	id := identity{
+		name:  subject,
+		roles: stringsClaim(claims[v.config.RolesClaim]),
+	}
+
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0)) {
+		return id, microerror.Maskf(unauthorizedError, "token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0)) {
+		return id, microerror.Maskf(unauthorizedError, "token not valid yet")
+	}
+	if v.config.Issuer != "" && claims["iss"] != v.config.Issuer {
+		return id, microerror.Maskf(unauthorizedError, "invalid token issuer")
+	}
+	if v.config.Audience != "" && !contains(stringsClaim(claims["aud"]), v.config.Audience) {
+		return id, microerror.Maskf(unauthorizedError, "invalid token audience")
+	}
+
+	return id, nil
+}
+
+// key returns the public key with the given ID. The key set is fetched again
+// when it is outdated or does not contain the key.
+func (v *verifier) key(kid string) (*rsa.PublicKey, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	age := time.Since(v.fetched)
+	if key, ok := v.keys[kid]; ok && age < jwksMaxAge {
+		return key, nil
+	}
+
+	if v.keys == nil || age > jwksMinAge {
+		keys, err := v.fetch()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		v.keys = keys
+		v.fetched = time.Now()
+	}
+
+	key, ok := v.keys[kid]
+	if !ok {
+		return nil, microerror.Maskf(unauthorizedError, "unknown token key %#q", kid)
+	}
+
+	return key, nil
+}
+
+// fetch reads the RSA keys of the configured key set by their IDs.
+func (v *verifier) fetch() (map[string]*rsa.PublicKey, error) {
+	var b []byte
+	if v.config.JWKSFile != "" {
+		var err error
+		b, err = ioutil.ReadFile(v.config.JWKSFile)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	} else {
+		resp, err := v.httpClient.Get(v.config.JWKSURL)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, microerror.Maskf(executionFailedError, "fetching JWKS: expected 200, got %d", resp.StatusCode)
+		}
+
+		b, err = ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var set jwks
+	err := json.Unmarshal(b, &set)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, microerror.Maskf(executionFailedError, "key %#q has invalid modulus", k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, microerror.Maskf(executionFailedError, "key %#q has invalid exponent", k.Kid)
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = json.Unmarshal(b, v)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// stringsClaim returns the values of a claim given either as string or as
+// list of strings.
+func stringsClaim(claim interface{}) []string {
+	switch c := claim.(type) {
+	case string:
+		return []string{c}
+	case []interface{}:
+		var values []string
+		for _, v := range c {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// keySet serves a JSON web key set with the given keys by their IDs.
+type keySet struct {
+	*httptest.Server
+
+	mutex sync.Mutex
+	keys  map[string]*rsa.PrivateKey
+}
+
+func newKeySet(t *testing.T) *keySet {
+	s := &keySet{keys: map[string]*rsa.PrivateKey{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		var set jwks
+		for kid, key := range s.keys {
+			set.Keys = append(set.Keys, jwk{
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				Kid: kid,
+				Kty: "RSA",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			})
+		}
+
+		err := json.NewEncoder(w).Encode(set)
+		if err != nil {
+			t.Errorf("encoding key set failed: %s", err)
+		}
+	}))
+
+	return s
+}
+
+func (s *keySet) Add(t *testing.T, kid string) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mutex.Lock()
+	s.keys[kid] = key
+	s.mutex.Unlock()
+
+	return key
+}
+
+// newToken returns a token with the given header and claims, signed with
+// RS256 by the given key.
+func newToken(t *testing.T, key *rsa.PrivateKey, header jwtHeader, claims map[string]interface{}) string {
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func Test_verifier_verify(t *testing.T) {
+	s := newKeySet(t)
+	defer s.Close()
+
+	key := s.Add(t, "key-1")
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := newVerifier(OIDCConfig{
+		Audience:   "auto-oncall",
+		Issuer:     "https://issuer.example.com",
+		JWKSURL:    s.URL,
+		RolesClaim: "groups",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"aud":    []string{"other", "auto-oncall"},
+			"exp":    now.Add(time.Hour).Unix(),
+			"groups": []string{"admin", "readonly"},
+			"iss":    "https://issuer.example.com",
+			"sub":    "jane",
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	testCases := []struct {
+		name             string
+		token            string
+		expectedErr      bool
+		expectedIdentity identity
+	}{
+		{
+			name:             "case 0: valid token",
+			token:            newToken(t, key, jwtHeader{Alg: "RS256", Kid: "key-1"}, claims(nil)),
+			expectedIdentity: identity{name: "jane", roles: []string{"admin", "readonly"}},
+		},
+		{
+			name:             "case 1: valid token with single audience and role",
+			token:            newToken(t, key, jwtHeader{Alg: "RS256", Kid: "key-1"}, claims(map[string]interface{}{"aud": "auto-oncall", "groups": "readonly"})),
+			expectedIdentity: identity{name: "jane", roles: []string{"readonly"}},
+		},
+		{
+			name:        "case 2: expired token",
+			token:       newToken(t, key, jwtHeader{Alg: "RS256", Kid: "key-1"}, claims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()})),
+			expectedErr: true,
+		},
+		{
+			name:        "case 3: token without expiry",
+			token:       newToken(t, key, jwtHeader{Alg: "RS256", Kid: "key-1"}, claims(map[string]interface{}{"exp": nil})),
+			expectedErr: true,
+		},
+		{
+			name:        "case 4: token not valid yet",
+			token:       newToken(t, key, jwtHeader{Alg: "RS256", Kid: "key-1"}, claims(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()})),
+			expectedErr: true,
+		},
+		{
+			name:        "case 5: token of another issuer",
+			token:       newToken(t, key, jwtHeader{Alg: "RS256", Kid: "key-1"}, claims(map[string]interface{}{"iss": "https://other.example.com"})),
+			expectedErr: true,
+		},
+		{
+			name:        "case 6: token for another audience",
+			token:       newToken(t, key, jwtHeader{Alg: "RS256", Kid: "key-1"}, claims(map[string]interface{}{"aud": "other"})),
+			expectedErr: true,
+		},
+		{
+			name:        "case 7: token with unsupported algorithm",
+			token:       newToken(t, key, jwtHeader{Alg: "HS256", Kid: "key-1"}, claims(nil)),
+			expectedErr: true,
+		},
+		{
+			name:        "case 8: token signed with another key",
+			token:       newToken(t, other, jwtHeader{Alg: "RS256", Kid: "key-1"}, claims(nil)),
+			expectedErr: true,
+		},
+		{
+			name:        "case 9: token signed with an unknown key",
+			token:       newToken(t, other, jwtHeader{Alg: "RS256", Kid: "key-2"}, claims(nil)),
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := v.verify(tc.token)
+			if tc.expectedErr && !IsUnauthorized(err) {
+				t.Fatalf("expected unauthorized error, got %#v", err)
+			}
+			if !tc.expectedErr && err != nil {
+				t.Fatalf("expected no error, got %#v", err)
+			}
+			if !tc.expectedErr && !reflect.DeepEqual(id, tc.expectedIdentity) {
+				t.Fatalf("expected identity %#v, got %#v", tc.expectedIdentity, id)
+			}
+		})
+	}
+}
+
+func Test_verifier_verify_RotatedKey(t *testing.T) {
+	s := newKeySet(t)
+	defer s.Close()
+
+	s.Add(t, "key-1")
+
+	v, err := newVerifier(OIDCConfig{
+		JWKSURL:    s.URL,
+		RolesClaim: "roles",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := s.Add(t, "key-2")
+	token := newToken(t, key, jwtHeader{Alg: "RS256", Kid: "key-2"}, map[string]interface{}{
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"sub": "jane",
+	})
+
+	// The key set was fetched right before, it is not fetched again for
+	// unknown keys yet.
+	_, err = v.verify(token)
+	if !IsUnauthorized(err) {
+		t.Fatalf("expected unauthorized error, got %#v", err)
+	}
+
+	v.mutex.Lock()
+	v.fetched = time.Now().Add(-2 * jwksMinAge)
+	v.mutex.Unlock()
+
+	id, err := v.verify(token)
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+	if id.name != "jane" {
+		t.Fatalf("expected subject %#q, got %#q", "jane", id.name)
+	}
+}
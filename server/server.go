@@ -1,20 +1,23 @@
 // Package server provides a server implementation to connect network transport
 // protocols and service business logic by defining server endpoints.
-//
 package server
 
 import (
+	"context"
+	"net/http"
 	"sync"
 
 	"github.com/giantswarm/microerror"
 	microserver "github.com/giantswarm/microkit/server"
 	"github.com/giantswarm/micrologger"
+	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/spf13/viper"
 
 	"github.com/giantswarm/auto-oncall/flag"
 	"github.com/giantswarm/auto-oncall/server/endpoint"
 	"github.com/giantswarm/auto-oncall/server/middleware"
 	"github.com/giantswarm/auto-oncall/service"
+	"github.com/giantswarm/auto-oncall/service/webhook"
 )
 
 // Config represents the configuration used to create a new server object.
@@ -44,9 +47,24 @@ func New(config Config) (*Server, error) {
 
 	var middlewareCollection *middleware.Middleware
 	{
+		var tokens []middleware.Token
+		err = config.Viper.UnmarshalKey(config.Flag.Service.Auth.Tokens, &tokens)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
 		c := middleware.Config{
 			Logger:  config.Logger,
 			Service: config.Service,
+
+			OIDC: middleware.OIDCConfig{
+				Audience:   config.Viper.GetString(config.Flag.Service.Auth.OIDC.Audience),
+				Issuer:     config.Viper.GetString(config.Flag.Service.Auth.OIDC.Issuer),
+				JWKSFile:   config.Viper.GetString(config.Flag.Service.Auth.OIDC.JWKS.File),
+				JWKSURL:    config.Viper.GetString(config.Flag.Service.Auth.OIDC.JWKS.URL),
+				RolesClaim: config.Viper.GetString(config.Flag.Service.Auth.OIDC.RolesClaim),
+			},
+			Tokens: tokens,
 		}
 
 		middlewareCollection, err = middleware.New(c)
@@ -77,14 +95,29 @@ func New(config Config) (*Server, error) {
 		// Internals.
 		bootOnce: sync.Once{},
 		config: microserver.Config{
-			Logger:      config.Logger,
-			ServiceName: config.ProjectName,
-			Viper:       config.Viper,
+			ErrorEncoder: errorEncoder,
+			Logger:       config.Logger,
+			ServiceName:  config.ProjectName,
+			Viper:        config.Viper,
 
 			Endpoints: []microserver.Endpoint{
+				endpointCollection.Assignment.Creator,
+				endpointCollection.Assignment.Extender,
+				endpointCollection.Assignment.Lister,
+				endpointCollection.Assignment.Revoker,
+				endpointCollection.Audit,
+				endpointCollection.Cleanup,
+				endpointCollection.DryRun,
+				endpointCollection.Healthz,
+				endpointCollection.Readyz,
 				endpointCollection.Version,
 				endpointCollection.Webhook,
 			},
+			// The request context is populated with request headers, so
+			// middlewares can check authorization.
+			RequestFuncs: []kithttp.RequestFunc{
+				kithttp.PopulateRequestContext,
+			},
 		},
 		shutdownOnce: sync.Once{},
 	}
@@ -92,6 +125,40 @@ func New(config Config) (*Server, error) {
 	return s, nil
 }
 
+// errorEncoder writes the status code of errors returned by endpoints and
+// middlewares. Unknown assignments are not found, invalid requests, e.g. with
+// a non-positive TTL, and unmapped users are rejected as invalid input.
+func errorEncoder(ctx context.Context, serverError error, w http.ResponseWriter) {
+	responseError, ok := serverError.(microserver.ResponseError)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if middleware.IsUnauthorized(responseError.Underlying()) {
+		responseError.SetCode(microserver.CodeInvalidCredentials)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if middleware.IsForbidden(responseError.Underlying()) {
+		responseError.SetCode(microserver.CodePermissionDenied)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if webhook.IsNotFound(responseError.Underlying()) {
+		responseError.SetCode(microserver.CodeResourceNotFound)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if webhook.IsInvalidRequest(responseError.Underlying()) || webhook.IsUserNotFound(responseError.Underlying()) {
+		responseError.SetCode(microserver.CodeInvalidInput)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
 func (s *Server) Boot() {
 	s.bootOnce.Do(func() {
 		// Here goes your custom boot logic for your server/endpoint/middleware, if
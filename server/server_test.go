@@ -0,0 +1,289 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	microserver "github.com/giantswarm/microkit/server"
+	"github.com/giantswarm/micrologger"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+
+	"github.com/giantswarm/auto-oncall/server/endpoint/assignment/creator"
+	"github.com/giantswarm/auto-oncall/server/endpoint/assignment/extender"
+	"github.com/giantswarm/auto-oncall/server/endpoint/assignment/revoker"
+	"github.com/giantswarm/auto-oncall/server/endpoint/cleanup"
+	"github.com/giantswarm/auto-oncall/server/middleware"
+	"github.com/giantswarm/auto-oncall/service"
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/audit"
+	"github.com/giantswarm/auto-oncall/service/directory"
+	"github.com/giantswarm/auto-oncall/service/notifier"
+	"github.com/giantswarm/auto-oncall/service/policy"
+	"github.com/giantswarm/auto-oncall/service/secret"
+	"github.com/giantswarm/auto-oncall/service/ttl"
+	"github.com/giantswarm/auto-oncall/service/webhook"
+)
+
+// stubProvider accepts all changes of assignments without doing anything.
+type stubProvider struct{}
+
+func (p stubProvider) Create(ctx context.Context, a *assignment.Assignment) error {
+	return nil
+}
+
+func (p stubProvider) Extend(ctx context.Context, a assignment.Assignment) error {
+	return nil
+}
+
+func (p stubProvider) Delete(ctx context.Context, a assignment.Assignment) error {
+	return nil
+}
+
+func (p stubProvider) Reconcile(ctx context.Context, active []assignment.Assignment) ([]assignment.Assignment, error) {
+	return active, nil
+}
+
+// newTestRouter returns a router serving the admin endpoints managing
+// assignments, with errors encoded like by the server. Authorization is not
+// checked.
+func newTestRouter(t *testing.T) http.Handler {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var webhookService *webhook.Service
+	{
+		a, err := audit.New(audit.Config{Logger: logger})
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := directory.New(nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := notifier.New(notifier.Config{Logger: logger})
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := policy.New(policy.Config{Directory: d, Logger: logger})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := secret.New(secret.Config{Logger: logger, Name: "secret", Value: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := ttl.New(ttl.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := webhook.Config{
+			Audit:      a,
+			HttpClient: http.DefaultClient,
+			Logger:     logger,
+
+			GithubToken:   s,
+			Handover:      webhook.HandoverReplace,
+			Notifier:      n,
+			Policy:        p,
+			Provider:      stubProvider{},
+			Registry:      assignment.NewRegistry(),
+			TTL:           r,
+			Users:         map[string]string{"johndoe": "john"},
+			WebhookSecret: s,
+		}
+
+		webhookService, err = webhook.New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var endpoints []microserver.Endpoint
+	{
+		s := &service.Service{Webhook: webhookService}
+		m := &middleware.Middleware{}
+
+		c, err := creator.New(creator.Config{Logger: logger, Middleware: m, Service: s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := extender.New(extender.Config{Logger: logger, Middleware: m, Service: s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := revoker.New(revoker.Config{Logger: logger, Middleware: m, Service: s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cl, err := cleanup.New(cleanup.Config{Logger: logger, Middleware: m, Service: s})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		endpoints = append(endpoints, c, e, r, cl)
+	}
+
+	// Errors are wrapped like by microkit before they are encoded.
+	encodeError := func(ctx context.Context, err error, w http.ResponseWriter) {
+		responseError, newErr := microserver.NewResponseError(microserver.ResponseErrorConfig{Underlying: err})
+		if newErr != nil {
+			t.Fatal(newErr)
+		}
+		errorEncoder(ctx, responseError, w)
+	}
+
+	router := mux.NewRouter()
+	for _, e := range endpoints {
+		router.Methods(e.Method()).Path(e.Path()).Handler(kithttp.NewServer(e.Endpoint(), e.Decoder(), e.Encoder(), kithttp.ServerErrorEncoder(encodeError)))
+	}
+
+	return router
+}
+
+func Test_Server_Assignments(t *testing.T) {
+	router := newTestRouter(t)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewReader([]byte(body))))
+		return w
+	}
+
+	w := do("POST", "/assignments", `{"repository": "aws-operator", "environment": "anteater", "user": "johndoe", "ttl": "1h"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating assignment, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created creator.Body
+	err := json.Unmarshal(w.Body.Bytes(), &created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Assignment == nil || created.Assignment.User != "john" {
+		t.Fatalf("expected assignment of %#q, got %#v", "john", created.Assignment)
+	}
+	name := created.Assignment.Name
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "case 0: create with unknown user",
+			method:         "POST",
+			path:           "/assignments",
+			body:           `{"repository": "aws-operator", "environment": "anteater", "user": "unknown", "ttl": "1h"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "case 1: create with negative ttl",
+			method:         "POST",
+			path:           "/assignments",
+			body:           `{"repository": "aws-operator", "environment": "anteater", "user": "johndoe", "ttl": "-1h"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "case 2: create with malformed ttl",
+			method:         "POST",
+			path:           "/assignments",
+			body:           `{"repository": "aws-operator", "environment": "anteater", "user": "johndoe", "ttl": "soon"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "case 3: create with unknown organization",
+			method:         "POST",
+			path:           "/assignments",
+			body:           `{"organization": "other", "repository": "aws-operator", "environment": "anteater", "user": "johndoe", "ttl": "1h"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "case 4: extend unknown assignment",
+			method:         "PUT",
+			path:           "/assignments/unknown",
+			body:           `{"ttl": "1h"}`,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "case 5: extend with zero ttl",
+			method:         "PUT",
+			path:           "/assignments/" + name,
+			body:           `{"ttl": "0s"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "case 6: extend assignment",
+			method:         "PUT",
+			path:           "/assignments/" + name,
+			body:           `{"ttl": "2h"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "case 7: revoke unknown assignment",
+			method:         "DELETE",
+			path:           "/assignments/unknown",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "case 8: revoke assignment",
+			method:         "DELETE",
+			path:           "/assignments/" + name,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "case 9: revoke assignment again",
+			method:         "DELETE",
+			path:           "/assignments/" + name,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := do(tc.method, tc.path, tc.body)
+			if w.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_Server_Cleanup(t *testing.T) {
+	router := newTestRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/assignments", bytes.NewReader([]byte(`{"repository": "aws-operator", "environment": "anteater", "user": "johndoe", "ttl": "1ms"}`))))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d creating assignment, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	for _, expected := range []int{1, 0} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/cleanup", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var response cleanup.Response
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.Deleted != expected {
+			t.Fatalf("expected %d deleted assignments, got %d", expected, response.Deleted)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package assignment describes on-call assignments derived from deployment
+// events, independently of the backend they are created in.
+package assignment
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// ResponderSchedule is the type of responders referring to the regular
+	// on-call schedule, as named in the backend. Alertmanager treats it as
+	// the name of a receiver.
+	ResponderSchedule = "schedule"
+	// ResponderUser is the type of responders referring to a user as
+	// configured in the user mapping.
+	ResponderUser = "user"
+)
+
+const (
+	namePrefix = "auto"
+)
+
+// Assignment is the on-call decision made for a single deployment. It puts
+// User on call for alerts of Repository in Environment until Expiry.
+type Assignment struct {
+	// Name identifies the assignment. It is used as name of all objects created
+	// in the backend.
+	Name string `json:"name"`
+	// Organization is the GitHub organization of the repository, empty for
+	// repositories of organizations not configured explicitly.
+	Organization string `json:"organization,omitempty"`
+	// Repository is the name of the deployed repository.
+	Repository string `json:"repository"`
+	// Ref is the deployed git reference.
+	Ref string `json:"ref"`
+	// Environment is the installation the repository was deployed to.
+	Environment string `json:"environment"`
+	// GithubLogin is the GitHub login of the deployer. It is empty when a
+	// schedule is on call instead, see Schedule.
+	GithubLogin string `json:"githubLogin"`
+	// Resolution tells how the deployer was resolved, e.g. from the creator
+	// of the deployment or the author of the deployed commit.
+	Resolution string `json:"resolution,omitempty"`
+	// User is the deployer as configured in the user mapping. It is empty
+	// when a schedule is on call instead, see Schedule.
+	User string `json:"user"`
+	// Team is the team owning the objects created in the backend, e.g. the
+	// Opsgenie team. The team of the provider is used when empty.
+	Team string `json:"team,omitempty"`
+	// Responders are paged together with the deployer.
+	Responders []Responder `json:"responders,omitempty"`
+	// Created is the point in time the assignment was made.
+	Created time.Time `json:"created"`
+	// Expiry is the point in time the assignment ends.
+	Expiry time.Time `json:"expiry"`
+	// Start is the point in time a deferred assignment is made, e.g. once
+	// the previous deployer is off call. Until then the assignment is
+	// pending and nothing is created for it in the backend.
+	Start *time.Time `json:"start,omitempty"`
+	// UntilSuperseded tells whether the assignment ends before its expiry
+	// when the repository is deployed to the environment again.
+	UntilSuperseded bool `json:"untilSuperseded,omitempty"`
+	// Delivery is the ID of the GitHub webhook delivery the assignment was
+	// made for.
+	Delivery string `json:"delivery,omitempty"`
+	// IDs are the identifiers of the objects created in the backend, keyed by
+	// object kind.
+	IDs map[string]string `json:"ids,omitempty"`
+}
+
+// Responder is an additional recipient of an assignment.
+type Responder struct {
+	// Type is the type of the responder, e.g. ResponderUser.
+	Type string `json:"type"`
+	// Name identifies the responder within its type.
+	Name string `json:"name"`
+}
+
+// New returns an assignment with a name encoding its expiry, e.g.
+// auto-aws-operator-master-gauss-johndoe-1546300800.
+func New(repository, ref, environment, githubLogin, user string, expiry time.Time) Assignment {
+	a := Assignment{
+		Repository:  repository,
+		Ref:         ref,
+		Environment: environment,
+		GithubLogin: githubLogin,
+		User:        user,
+		Created:     time.Now().UTC(),
+		Expiry:      expiry.UTC(),
+	}
+
+	a.Name = fmt.Sprintf("%s-%s-%s-%s-%s-%d", namePrefix, a.Repository, a.Ref, a.Environment, a.GithubLogin, a.Expiry.Unix())
+
+	return a
+}
+
+// SetID records the ID of an object of the given kind created in the backend.
+// The IDs are copied, so copies of the assignment are not affected.
+func (a *Assignment) SetID(kind, id string) {
+	ids := map[string]string{}
+	for k, v := range a.IDs {
+		ids[k] = v
+	}
+	ids[kind] = id
+
+	a.IDs = ids
+}
+
+// Pending tells whether the assignment is deferred and not made yet.
+func (a Assignment) Pending() bool {
+	return a.Start != nil
+}
+
+// Schedule returns the schedule on call instead of a user, e.g. the schedule
+// of the team of an absent deployer. It returns false for assignments of a
+// user.
+func (a Assignment) Schedule() (string, bool) {
+	if a.User != "" {
+		return "", false
+	}
+	for _, r := range a.Responders {
+		if r.Type == ResponderSchedule {
+			return r.Name, true
+		}
+	}
+
+	return "", false
+}
+
+// Assignee describes who is on call, the GitHub login of the deployer or the
+// schedule on call instead.
+func (a Assignment) Assignee() string {
+	if schedule, ok := a.Schedule(); ok {
+		return fmt.Sprintf("schedule %s", schedule)
+	}
+
+	return a.GithubLogin
+}
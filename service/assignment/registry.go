@@ -0,0 +1,247 @@
+package assignment
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Registry keeps track of the assignments created by the service. It is
+// either kept in memory only or persisted to a file.
+type Registry struct {
+	assignments map[string]Assignment
+	mutex       sync.Mutex
+	path        string
+}
+
+// NewRegistry creates an empty registry kept in memory only.
+func NewRegistry() *Registry {
+	r := &Registry{
+		assignments: map[string]Assignment{},
+	}
+
+	return r
+}
+
+// NewFileRegistry creates a registry persisted to the file at the given path.
+// Assignments stored in the file by an earlier run are loaded.
+func NewFileRegistry(path string) (*Registry, error) {
+	r := &Registry{
+		assignments: map[string]Assignment{},
+		path:        path,
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var assignments []Assignment
+	err = json.Unmarshal(b, &assignments)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	for _, a := range assignments {
+		r.assignments[a.Name] = a
+	}
+
+	return r, nil
+}
+
+// Persistent tells whether the registry survives restarts.
+func (r *Registry) Persistent() bool {
+	return r.path != ""
+}
+
+// Add adds the given assignment to the registry. An assignment with the same
+// name is replaced.
+func (r *Registry) Add(a Assignment) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.assignments[a.Name] = a
+
+	err := r.write()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Remove removes the assignment with the given name from the registry.
+func (r *Registry) Remove(name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.assignments, name)
+
+	err := r.write()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Get returns the unexpired assignment with the given name, which may be
+// pending.
+func (r *Registry) Get(name string) (Assignment, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	a, ok := r.assignments[name]
+	if !ok || !a.Expiry.After(time.Now()) {
+		return Assignment{}, false
+	}
+
+	return a, true
+}
+
+// All returns all unexpired assignments that are not pending, ordered by
+// expiry.
+func (r *Registry) All() []Assignment {
+	return r.filter(func(a Assignment) bool {
+		return true
+	})
+}
+
+// Active returns the unexpired assignments of the given repository of the
+// given organization and environment that are not pending, ordered by expiry.
+func (r *Registry) Active(organization, repository, environment string) []Assignment {
+	return r.filter(func(a Assignment) bool {
+		return a.Organization == organization && a.Repository == repository && a.Environment == environment
+	})
+}
+
+// Find returns the unexpired assignment putting the given user on call for
+// the given repository of the given organization and environment.
+func (r *Registry) Find(organization, repository, environment, user string) (Assignment, bool) {
+	active := r.Active(organization, repository, environment)
+
+	// The assignment expiring last wins, should there be several.
+	for i := len(active) - 1; i >= 0; i-- {
+		if active[i].User == user {
+			return active[i], true
+		}
+	}
+
+	return Assignment{}, false
+}
+
+// Pending returns the unexpired pending assignments, ordered by start.
+func (r *Registry) Pending() []Assignment {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+
+	var pending []Assignment
+	for _, a := range r.assignments {
+		if a.Expiry.After(now) && a.Pending() {
+			pending = append(pending, a)
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Start.Before(*pending[j].Start)
+	})
+
+	return pending
+}
+
+// Expired returns the expired assignments still in the registry, pending or
+// not. They stay in the registry until they are removed.
+func (r *Registry) Expired() []Assignment {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+
+	var expired []Assignment
+	for _, a := range r.assignments {
+		if !a.Expiry.After(now) {
+			expired = append(expired, a)
+		}
+	}
+
+	return expired
+}
+
+// filter returns the unexpired assignments that are not pending and match
+// the given function, ordered by expiry.
+func (r *Registry) filter(match func(a Assignment) bool) []Assignment {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+
+	var active []Assignment
+	for _, a := range r.assignments {
+		if a.Expiry.After(now) && !a.Pending() && match(a) {
+			active = append(active, a)
+		}
+	}
+
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].Expiry.Before(active[j].Expiry)
+	})
+
+	return active
+}
+
+// Check verifies that the registry can be persisted by writing the stored
+// assignments again. It always succeeds for registries kept in memory.
+func (r *Registry) Check() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	err := r.write()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// write stores all assignments in the file of the registry, if any. The file
+// is written to a temporary file first and renamed afterwards, so it is never
+// left partially written.
+func (r *Registry) write() error {
+	if r.path == "" {
+		return nil
+	}
+
+	assignments := []Assignment{}
+	for _, a := range r.assignments {
+		assignments = append(assignments, a)
+	}
+	sort.Slice(assignments, func(i, j int) bool {
+		return assignments[i].Name < assignments[j].Name
+	})
+
+	b, err := json.MarshalIndent(assignments, "", "  ")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	tmp := filepath.Join(filepath.Dir(r.path), "."+filepath.Base(r.path)+".tmp")
+	err = ioutil.WriteFile(tmp, b, 0600)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = os.Rename(tmp, r.path)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
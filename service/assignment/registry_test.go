@@ -0,0 +1,114 @@
+package assignment
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Registry_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auto-oncall-registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "assignments.json")
+
+	r, err := NewFileRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Persistent() {
+		t.Fatal("expected persistent registry")
+	}
+	if len(r.All()) != 0 {
+		t.Fatalf("expected empty registry without file, got %#v", r.All())
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	start := now.Add(time.Hour)
+
+	active := New("aws-operator", "v1.0.0", "anteater", "johndoe", "john", now.Add(time.Hour))
+	active.SetID("route", "abc")
+	pending := New("aws-operator", "v1.1.0", "anteater", "janedoe", "jane", now.Add(2*time.Hour))
+	pending.Start = &start
+	expired := New("aws-operator", "v0.9.0", "anteater", "janedoe", "jane", now.Add(-time.Minute))
+
+	for _, a := range []Assignment{active, pending, expired} {
+		err = r.Add(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The file is written to a temporary file renamed afterwards, which must
+	// not be left behind.
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "assignments.json" {
+		t.Fatalf("expected only the state file, got %d files", len(files))
+	}
+
+	// The registry is loaded again from the file on startup.
+	reloaded, err := NewFileRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all := reloaded.All()
+	if len(all) != 1 || !reflect.DeepEqual(all[0].IDs, active.IDs) || !all[0].Expiry.Equal(active.Expiry) {
+		t.Fatalf("expected active assignment %#v, got %#v", active, all)
+	}
+	p := reloaded.Pending()
+	if len(p) != 1 || p[0].Name != pending.Name || !p[0].Start.Equal(start) {
+		t.Fatalf("expected pending assignment %#q starting %s, got %#v", pending.Name, start, p)
+	}
+	e := reloaded.Expired()
+	if len(e) != 1 || e[0].Name != expired.Name {
+		t.Fatalf("expected expired assignment %#q, got %#v", expired.Name, e)
+	}
+	if _, ok := reloaded.Get(expired.Name); ok {
+		t.Fatalf("expected expired assignment %#q not to be found", expired.Name)
+	}
+	if _, ok := reloaded.Find("", "aws-operator", "anteater", "jane"); ok {
+		t.Fatal("expected pending assignment not to be found as active")
+	}
+
+	err = reloaded.Remove(expired.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err = NewFileRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.Expired()) != 0 {
+		t.Fatalf("expected removed assignment to be gone after reload, got %#v", reloaded.Expired())
+	}
+}
+
+func Test_Registry_File_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auto-oncall-registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "assignments.json")
+	err = ioutil.WriteFile(path, []byte("{"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewFileRegistry(path)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
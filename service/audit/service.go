@@ -0,0 +1,249 @@
+// Package audit records security relevant events, like denied requests and
+// changes of assignments, in the audit log.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	// OutcomeDenied is the outcome of requests rejected by authentication or
+	// authorization.
+	OutcomeDenied = "denied"
+	// OutcomeFailed is the outcome of changes failing in the provider or
+	// registry.
+	OutcomeFailed = "failed"
+	// OutcomeSucceeded is the outcome of changes made.
+	OutcomeSucceeded = "succeeded"
+
+	// ActionCreate is the action of creating an assignment.
+	ActionCreate = "create"
+	// ActionDelete is the action of deleting an assignment.
+	ActionDelete = "delete"
+	// ActionExtend is the action of extending an assignment.
+	ActionExtend = "extend"
+	// ActionPolicy is the decision of the policy on an assignment before it
+	// is made. The outcome is the action taken by the policy, e.g. skip.
+	ActionPolicy = "policy"
+
+	// OriginAdmin is the origin of changes requested through the admin API.
+	OriginAdmin = "admin"
+	// OriginReaper is the origin of deletions of expired assignments.
+	OriginReaper = "reaper"
+	// OriginWebhook is the origin of changes caused by GitHub webhooks.
+	OriginWebhook = "webhook"
+)
+
+const (
+	// bufferSize is the number of entries kept in memory for queries when
+	// the audit log is written to stdout.
+	bufferSize = 1000
+)
+
+// Entry is a single record of the audit log.
+type Entry struct {
+	// Time is the point in time the entry was recorded.
+	Time time.Time `json:"time"`
+	// Origin tells what caused the event, e.g. OriginWebhook.
+	Origin string `json:"origin,omitempty"`
+	// Actor identifies who caused the event, e.g. the name of a token, the
+	// subject of an OIDC token or the creator of a deployment.
+	Actor string `json:"actor,omitempty"`
+	// Action is what was done or attempted, e.g. ActionCreate or the
+	// endpoint name.
+	Action string `json:"action"`
+	// Outcome is the result of the action, e.g. OutcomeDenied.
+	Outcome string `json:"outcome"`
+	// Reason optionally explains the action or outcome.
+	Reason string `json:"reason,omitempty"`
+	// Source is the remote address of the request, if any.
+	Source string `json:"source,omitempty"`
+
+	// Assignment is the name of the changed assignment.
+	Assignment string `json:"assignment,omitempty"`
+	// Delivery is the ID of the GitHub webhook delivery the assignment was
+	// made for.
+	Delivery string `json:"delivery,omitempty"`
+	// Organization is the GitHub organization of the repository, if
+	// configured explicitly.
+	Organization string `json:"organization,omitempty"`
+	Repository   string `json:"repository,omitempty"`
+	Environment  string `json:"environment,omitempty"`
+	Ref          string `json:"ref,omitempty"`
+	// GithubLogin is the resolved GitHub login of the deployer and Resolution
+	// tells how it was resolved.
+	GithubLogin string `json:"githubLogin,omitempty"`
+	Resolution  string `json:"resolution,omitempty"`
+	// User is the deployer as configured in the user mapping.
+	User string `json:"user,omitempty"`
+	// Created and Deleted are the IDs of the objects created and deleted in
+	// the backend, keyed by object kind.
+	Created map[string]string `json:"created,omitempty"`
+	Deleted map[string]string `json:"deleted,omitempty"`
+	// Expiry is the expiry of the assignment after the change.
+	Expiry *time.Time `json:"expiry,omitempty"`
+	// DryRun tells that the change was recorded in dry-run mode and not
+	// applied in the backend.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// Query selects entries of the audit log. Empty fields match all entries.
+type Query struct {
+	// From and To limit the time range of entries, both inclusive.
+	From time.Time
+	To   time.Time
+	// User matches the actor, the GitHub login and the mapped user.
+	User string
+}
+
+// Config represents the configuration used to create an audit service.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// DryRun marks all entries as recorded in dry-run mode.
+	DryRun bool
+	// Path is the file the audit log is appended to. It is written to stdout
+	// when empty, in which case only the latest entries can be queried.
+	Path string
+}
+
+// Service writes the audit log as JSON lines.
+type Service struct {
+	logger micrologger.Logger
+
+	dryRun bool
+	path   string
+
+	mutex   sync.Mutex
+	file    *os.File
+	writer  io.Writer
+	entries []Entry
+}
+
+// New creates a new configured audit service.
+func New(config Config) (*Service, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	s := &Service{
+		logger: config.Logger,
+
+		dryRun: config.DryRun,
+		path:   config.Path,
+
+		writer: os.Stdout,
+	}
+
+	if config.Path != "" {
+		f, err := os.OpenFile(config.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		s.file = f
+		s.writer = f
+	}
+
+	return s, nil
+}
+
+// Record appends the given entry to the audit log. The time of the entry is
+// set if it is not given.
+func (s *Service) Record(e Entry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	e.DryRun = s.dryRun
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		s.logger.Log("level", "error", "message", "encoding audit entry failed", "stack", fmt.Sprintf("%#v", err))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, err = s.writer.Write(append(b, '\n'))
+	if err != nil {
+		s.logger.Log("level", "error", "message", "writing audit entry failed", "stack", fmt.Sprintf("%#v", err))
+	}
+	if s.file != nil {
+		err = s.file.Sync()
+		if err != nil {
+			s.logger.Log("level", "error", "message", "syncing audit log failed", "stack", fmt.Sprintf("%#v", err))
+		}
+	} else {
+		s.entries = append(s.entries, e)
+		if len(s.entries) > bufferSize {
+			s.entries = s.entries[len(s.entries)-bufferSize:]
+		}
+	}
+}
+
+// Query returns the entries matching the given query, oldest first.
+func (s *Service) Query(q Query) ([]Entry, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		var entries []Entry
+		for _, e := range s.entries {
+			if q.matches(e) {
+				entries = append(entries, e)
+			}
+		}
+
+		return entries, nil
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			// Lines cut off by a crash while writing are skipped.
+			continue
+		}
+		if q.matches(e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return entries, nil
+}
+
+func (q Query) matches(e Entry) bool {
+	if !q.From.IsZero() && e.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && e.Time.After(q.To) {
+		return false
+	}
+	if q.User != "" && q.User != e.Actor && q.User != e.GithubLogin && q.User != e.User {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,204 @@
+package availability
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/secret"
+)
+
+const (
+	// CalendarName is the name of the iCalendar source.
+	CalendarName = "calendar"
+
+	// DefaultRefreshInterval is the interval calendars are fetched again in
+	// by default.
+	DefaultRefreshInterval = 15 * time.Minute
+
+	// maxCalendarSize is the size of calendars read at most.
+	maxCalendarSize = 10 << 20
+	timeFormat      = "2006-01-02 15:04 MST"
+)
+
+// CalendarConfig represents the configuration used to create an iCalendar
+// source.
+type CalendarConfig struct {
+	HttpClient *http.Client
+	Logger     micrologger.Logger
+
+	// RefreshInterval is the interval calendars are fetched again in. It
+	// defaults to DefaultRefreshInterval.
+	RefreshInterval time.Duration
+}
+
+// Calendar tells users absent during events of their iCalendar files or
+// those of their team, e.g. exported from a vacation planner. Calendars are
+// cached for the refresh interval.
+type Calendar struct {
+	httpClient *http.Client
+	logger     micrologger.Logger
+
+	refreshInterval time.Duration
+
+	mutex  sync.Mutex
+	cached map[string]calendar
+}
+
+// calendar is a fetched calendar.
+type calendar struct {
+	events  []event
+	fetched time.Time
+}
+
+// NewCalendar creates a new configured iCalendar source.
+func NewCalendar(config CalendarConfig) (*Calendar, error) {
+	if config.HttpClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.HttpClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.RefreshInterval == 0 {
+		config.RefreshInterval = DefaultRefreshInterval
+	}
+
+	c := &Calendar{
+		httpClient: config.HttpClient,
+		logger:     config.Logger,
+
+		refreshInterval: config.RefreshInterval,
+
+		cached: map[string]calendar{},
+	}
+
+	return c, nil
+}
+
+// Name returns CalendarName.
+func (c *Calendar) Name() string {
+	return CalendarName
+}
+
+// Absence tells the subject absent during any event of their calendars.
+// Calendars failing to be fetched are skipped, the error is returned if no
+// other calendar tells the subject absent.
+func (c *Calendar) Absence(ctx context.Context, s Subject, t time.Time) (string, error) {
+	location := s.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	var failed []string
+	for _, source := range s.Calendars {
+		events, err := c.events(ctx, source)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", describe(source), microerror.Cause(err).Error()))
+			continue
+		}
+
+		for _, e := range events {
+			end, ok := e.during(t, location)
+			if ok {
+				return fmt.Sprintf("absent until %s according to their calendar", end.In(location).Format(timeFormat)), nil
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return "", microerror.Maskf(executionFailedError, "%s", strings.Join(failed, "; "))
+	}
+
+	return "", nil
+}
+
+// events returns the events of the given calendar, fetching it if it is not
+// cached or the cached calendar is outdated. An outdated calendar is used when
+// fetching it fails.
+func (c *Calendar) events(ctx context.Context, source string) ([]event, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cached, ok := c.cached[source]
+	if ok && time.Since(cached.fetched) < c.refreshInterval {
+		return cached.events, nil
+	}
+
+	events, err := c.fetch(ctx, source)
+	if err != nil && ok {
+		c.logger.Log("level", "warning", "message", fmt.Sprintf("fetching %s failed, using calendar fetched at %s", describe(source), cached.fetched.Format(time.RFC3339)), "stack", fmt.Sprintf("%#v", err))
+		return cached.events, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	c.cached[source] = calendar{events: events, fetched: time.Now()}
+
+	return events, nil
+}
+
+// fetch reads and parses the calendar at the given path or URL.
+func (c *Calendar) fetch(ctx context.Context, source string) ([]event, error) {
+	var r io.ReadCloser
+	if isURL(source) {
+		req, err := http.NewRequest("GET", source, nil)
+		if err != nil {
+			return nil, microerror.Maskf(executionFailedError, "invalid URL")
+		}
+		req = req.WithContext(secret.WithURL(ctx))
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			// The error contains the URL, which may contain a secret token
+			// like those of private calendar links.
+			return nil, microerror.Maskf(executionFailedError, "request failed")
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, microerror.Maskf(executionFailedError, "expected 200, got %d", resp.StatusCode)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		r = f
+	}
+	defer r.Close()
+
+	events, err := parseCalendar(io.LimitReader(r, maxCalendarSize))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return events, nil
+}
+
+// describe names the given calendar in logs and errors. URLs are reduced to
+// their host, since private calendar links contain secret tokens.
+func describe(source string) string {
+	if !isURL(source) {
+		return fmt.Sprintf("calendar %#q", source)
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return "calendar"
+	}
+
+	return fmt.Sprintf("calendar at %s", u.Host)
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
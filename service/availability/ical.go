@@ -0,0 +1,273 @@
+package availability
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	dateFormat         = "20060102"
+	dateTimeFormat     = "20060102T150405"
+	utcDateTimeFormat  = "20060102T150405Z"
+	statusCancelled    = "CANCELLED"
+	transpTransparent  = "TRANSPARENT"
+	valueDate          = "DATE"
+	calendarBeginLine  = "BEGIN:VCALENDAR"
+	eventBeginLine     = "BEGIN:VEVENT"
+	eventEndLine       = "END:VEVENT"
+	maxCalendarLineLen = 1 << 20
+)
+
+// durationExpression matches iCalendar durations like P1D or PT1H30M.
+var durationExpression = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// event is the time range of an event of an iCalendar file. Floating events,
+// i.e. all-day events and events without time zone, are kept in UTC and are
+// moved to the time zone of the subject when checked.
+type event struct {
+	start    time.Time
+	end      time.Time
+	floating bool
+}
+
+// during returns the end of the event if the given point in time is within
+// the event, interpreting floating events in the given time zone.
+func (e event) during(t time.Time, location *time.Location) (time.Time, bool) {
+	start, end := e.start, e.end
+	if e.floating {
+		start = inLocation(start, location)
+		end = inLocation(end, location)
+	}
+
+	if t.Before(start) || !t.Before(end) {
+		return time.Time{}, false
+	}
+
+	return end, true
+}
+
+// parseCalendar returns the events of the given iCalendar file. Events marked
+// as cancelled or transparent, i.e. not blocking time, and events whose times
+// cannot be parsed are left out. Recurrence rules are not expanded, only the
+// first occurrence of recurring events is returned.
+func parseCalendar(r io.Reader) ([]event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if len(lines) == 0 || lines[0] != calendarBeginLine {
+		return nil, microerror.Maskf(invalidCalendarError, "expected %#q", calendarBeginLine)
+	}
+
+	var events []event
+	var properties map[string]property
+	// nested counts the components nested in the current event, e.g.
+	// alarms, whose properties do not belong to the event.
+	var nested int
+	for _, line := range lines {
+		switch {
+		case line == eventBeginLine:
+			properties = map[string]property{}
+			nested = 0
+
+		case properties != nil && strings.HasPrefix(line, "BEGIN:"):
+			nested++
+
+		case properties != nil && nested > 0 && strings.HasPrefix(line, "END:"):
+			nested--
+
+		case properties != nil && nested > 0:
+			// Properties of nested components are ignored.
+
+		case line == eventEndLine && properties != nil:
+			e, ok := newEvent(properties)
+			if ok {
+				events = append(events, e)
+			}
+			properties = nil
+
+		case properties != nil:
+			p := parseProperty(line)
+			properties[p.name] = p
+		}
+	}
+
+	return events, nil
+}
+
+// newEvent returns the event of the given properties of a VEVENT component.
+// It returns false for events not blocking time or with invalid times.
+func newEvent(properties map[string]property) (event, bool) {
+	if properties["STATUS"].value == statusCancelled || properties["TRANSP"].value == transpTransparent {
+		return event{}, false
+	}
+
+	dtstart, ok := properties["DTSTART"]
+	if !ok {
+		return event{}, false
+	}
+	start, floating, err := parseTime(dtstart)
+	if err != nil {
+		return event{}, false
+	}
+	allDay := dtstart.params["VALUE"] == valueDate || len(dtstart.value) == len(dateFormat)
+
+	e := event{
+		start:    start,
+		floating: floating,
+	}
+
+	if dtend, ok := properties["DTEND"]; ok {
+		e.end, _, err = parseTime(dtend)
+		if err != nil {
+			return event{}, false
+		}
+	} else if duration, ok := properties["DURATION"]; ok {
+		d, err := parseDuration(duration.value)
+		if err != nil {
+			return event{}, false
+		}
+		e.end = start.Add(d)
+	} else if allDay {
+		e.end = start.AddDate(0, 0, 1)
+	} else {
+		e.end = start
+	}
+
+	return e, true
+}
+
+// property is a content line of an iCalendar file, e.g.
+// DTSTART;TZID=Europe/Berlin:20190101T090000.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func parseProperty(line string) property {
+	p := property{
+		params: map[string]string{},
+	}
+
+	i := strings.Index(line, ":")
+	if i < 0 {
+		p.name = strings.ToUpper(line)
+		return p
+	}
+	p.value = line[i+1:]
+
+	parts := strings.Split(line[:i], ";")
+	p.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			p.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+
+	return p
+}
+
+// parseTime parses the date or date-time of the given property. It tells
+// whether the time is floating, i.e. without time zone.
+func parseTime(p property) (time.Time, bool, error) {
+	switch {
+	case p.params["VALUE"] == valueDate || len(p.value) == len(dateFormat):
+		t, err := time.Parse(dateFormat, p.value)
+		if err != nil {
+			return time.Time{}, false, microerror.Maskf(invalidCalendarError, "invalid date %#q", p.value)
+		}
+		return t, true, nil
+
+	case strings.HasSuffix(p.value, "Z"):
+		t, err := time.Parse(utcDateTimeFormat, p.value)
+		if err != nil {
+			return time.Time{}, false, microerror.Maskf(invalidCalendarError, "invalid date-time %#q", p.value)
+		}
+		return t, false, nil
+
+	case p.params["TZID"] != "":
+		location, err := time.LoadLocation(p.params["TZID"])
+		if err == nil {
+			t, err := time.ParseInLocation(dateTimeFormat, p.value, location)
+			if err != nil {
+				return time.Time{}, false, microerror.Maskf(invalidCalendarError, "invalid date-time %#q", p.value)
+			}
+			return t, false, nil
+		}
+		// Time zones defined within the calendar, e.g. by Outlook, are not
+		// known to Go, the time is treated as floating then.
+		fallthrough
+
+	default:
+		t, err := time.Parse(dateTimeFormat, p.value)
+		if err != nil {
+			return time.Time{}, false, microerror.Maskf(invalidCalendarError, "invalid date-time %#q", p.value)
+		}
+		return t, true, nil
+	}
+}
+
+// parseDuration parses iCalendar durations like P1D or PT1H30M.
+func parseDuration(s string) (time.Duration, error) {
+	m := durationExpression.FindStringSubmatch(s)
+	if m == nil {
+		return 0, microerror.Maskf(invalidCalendarError, "invalid duration %#q", s)
+	}
+
+	var d time.Duration
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	for i, unit := range units {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, microerror.Maskf(invalidCalendarError, "invalid duration %#q", s)
+		}
+		d += time.Duration(n) * unit
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+
+	return d, nil
+}
+
+// unfold returns the content lines of an iCalendar file, joining lines
+// folded onto continuation lines starting with a space or tab.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxCalendarLineLen)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return lines, nil
+}
+
+// inLocation returns the wall clock time of the given UTC time in the given
+// time zone.
+func inLocation(t time.Time, location *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, location)
+}
@@ -0,0 +1,37 @@
+// Package availability tells whether users are absent, e.g. on vacation,
+// so that they are not put on call.
+package availability
+
+import (
+	"context"
+	"time"
+)
+
+// Subject is the user whose availability is checked.
+type Subject struct {
+	// GithubLogin is the GitHub login of the user.
+	GithubLogin string
+	// User is the user as configured in the user mapping, e.g. the Opsgenie
+	// username.
+	User string
+	// Calendars are the paths or URLs of the iCalendar files of the user and
+	// their team.
+	Calendars []string
+	// Location is the time zone dates without time, like those of all-day
+	// events, are in. It defaults to UTC.
+	Location *time.Location
+	// Schedule is the regular on-call schedule of the team of the user, if
+	// any.
+	Schedule string
+}
+
+// Source tells whether users are absent.
+type Source interface {
+	// Name identifies the source in logs and decisions.
+	Name() string
+	// Absence returns a description of the absence of the given subject at
+	// the given point in time, e.g. "absent until 2019-01-07 00:00 UTC", or
+	// an empty string if the subject is available as far as the source
+	// knows.
+	Absence(ctx context.Context, s Subject, t time.Time) (string, error)
+}
@@ -0,0 +1,154 @@
+// Package directory describes the engineers who can be put on call, with
+// the details needed beyond the user mapping, e.g. how to reach them in chat.
+package directory
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// User is an entry of the user directory.
+type User struct {
+	// Github is the GitHub login of the user. It identifies the entry.
+	Github string
+	// Slack is the Slack member ID of the user, e.g. U0123ABCD.
+	Slack string
+	// Timezone is the IANA time zone the working hours are in, e.g.
+	// Europe/Berlin. It defaults to UTC.
+	Timezone string
+	// WorkingHours are the hours the user works on working days, e.g.
+	// 09:00-17:30. Policies based on working hours do not apply to users
+	// without working hours.
+	WorkingHours string
+	// WorkingDays are the days of the week the user works, e.g. monday. They
+	// default to Monday to Friday.
+	WorkingDays []string
+	// Team is the name of the team of the user, if any.
+	Team string
+	// Calendar is the path or URL of an iCalendar file whose events are
+	// absences of the user, e.g. vacations.
+	Calendar string
+	// Backup is the GitHub login of the user put on call instead while the
+	// user is absent.
+	Backup string
+}
+
+// Team is a group of users sharing absences and an on-call schedule.
+type Team struct {
+	// Name identifies the team.
+	Name string
+	// Calendar is the path or URL of an iCalendar file whose events are
+	// absences of all members, e.g. public holidays.
+	Calendar string
+	// Schedule is the regular on-call schedule of the team, as named in the
+	// on-call backend.
+	Schedule string
+}
+
+// Directory looks up users by GitHub login and teams by name.
+type Directory struct {
+	locations    map[string]*time.Location
+	teams        map[string]Team
+	users        map[string]User
+	workingHours map[string]WorkingHours
+}
+
+// New creates a directory of the given users and teams. GitHub logins and
+// team names must be given and unique. Teams of users must be given.
+func New(users []User, teams []Team) (*Directory, error) {
+	d := &Directory{
+		locations:    map[string]*time.Location{},
+		teams:        map[string]Team{},
+		users:        map[string]User{},
+		workingHours: map[string]WorkingHours{},
+	}
+
+	for i, t := range teams {
+		if t.Name == "" {
+			return nil, microerror.Maskf(invalidConfigError, "team %d: name must not be empty", i)
+		}
+		if _, ok := d.teams[t.Name]; ok {
+			return nil, microerror.Maskf(invalidConfigError, "team %#q configured twice", t.Name)
+		}
+
+		d.teams[t.Name] = t
+	}
+
+	for i, u := range users {
+		if u.Github == "" {
+			return nil, microerror.Maskf(invalidConfigError, "user %d: github must not be empty", i)
+		}
+		if _, ok := d.users[u.Github]; ok {
+			return nil, microerror.Maskf(invalidConfigError, "user %#q configured twice", u.Github)
+		}
+
+		if _, ok := d.teams[u.Team]; u.Team != "" && !ok {
+			return nil, microerror.Maskf(invalidConfigError, "user %#q: team %#q not configured", u.Github, u.Team)
+		}
+		if u.Backup == u.Github {
+			return nil, microerror.Maskf(invalidConfigError, "user %#q: backup must be another user", u.Github)
+		}
+
+		location, err := time.LoadLocation(u.Timezone)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "user %#q: invalid timezone %#q", u.Github, u.Timezone)
+		}
+		d.locations[u.Github] = location
+
+		if u.WorkingHours != "" {
+			w, err := parseWorkingHours(u)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			d.workingHours[u.Github] = w
+		}
+
+		d.users[u.Github] = u
+	}
+
+	return d, nil
+}
+
+// Get returns the user with the given GitHub login.
+func (d *Directory) Get(githubLogin string) (User, bool) {
+	u, ok := d.users[githubLogin]
+	return u, ok
+}
+
+// WorkingHours returns the working hours of the user with the given GitHub
+// login, if configured.
+func (d *Directory) WorkingHours(githubLogin string) (WorkingHours, bool) {
+	w, ok := d.workingHours[githubLogin]
+	return w, ok
+}
+
+// Location returns the time zone of the user with the given GitHub login. It
+// is UTC for unknown users and users without time zone.
+func (d *Directory) Location(githubLogin string) *time.Location {
+	location, ok := d.locations[githubLogin]
+	if !ok {
+		return time.UTC
+	}
+	return location
+}
+
+// Team returns the team of the user with the given GitHub login, if any.
+func (d *Directory) Team(githubLogin string) (Team, bool) {
+	u, ok := d.users[githubLogin]
+	if !ok {
+		return Team{}, false
+	}
+	t, ok := d.teams[u.Team]
+	return t, ok
+}
+
+// Users returns all users of the directory.
+func (d *Directory) Users() []User {
+	var users []User
+	for _, u := range d.users {
+		users = append(users, u)
+	}
+
+	return users
+}
@@ -0,0 +1,125 @@
+package directory
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	clockFormat = "15:04"
+)
+
+var (
+	defaultWorkingDays = []string{"monday", "tuesday", "wednesday", "thursday", "friday"}
+
+	weekdays = map[string]time.Weekday{
+		"sunday":    time.Sunday,
+		"monday":    time.Monday,
+		"tuesday":   time.Tuesday,
+		"wednesday": time.Wednesday,
+		"thursday":  time.Thursday,
+		"friday":    time.Friday,
+		"saturday":  time.Saturday,
+	}
+)
+
+// WorkingHours are the hours a user works in, in their time zone.
+type WorkingHours struct {
+	location *time.Location
+	days     map[time.Weekday]bool
+	start    clock
+	end      clock
+}
+
+// clock is a time of day.
+type clock struct {
+	hour   int
+	minute int
+}
+
+// End returns the end of the working hours the given point in time is in. It
+// returns false outside of working hours.
+func (w WorkingHours) End(t time.Time) (time.Time, bool) {
+	t = t.In(w.location)
+	if !w.days[t.Weekday()] {
+		return time.Time{}, false
+	}
+
+	start := w.start.on(t, w.location)
+	end := w.end.on(t, w.location)
+	if t.Before(start) || !t.Before(end) {
+		return time.Time{}, false
+	}
+
+	return end.UTC(), true
+}
+
+// String returns the working hours with their time zone, e.g. 09:00-17:30
+// Europe/Berlin.
+func (w WorkingHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d %s", w.start.hour, w.start.minute, w.end.hour, w.end.minute, w.location)
+}
+
+// on returns the point in time of the clock on the day of the given point in
+// time.
+func (c clock) on(t time.Time, location *time.Location) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, c.hour, c.minute, 0, 0, location)
+}
+
+// parseWorkingHours parses the working hours of the given user, given like
+// 09:00-17:30, on their working days in their time zone.
+func parseWorkingHours(u User) (WorkingHours, error) {
+	location, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return WorkingHours{}, microerror.Maskf(invalidConfigError, "user %#q: invalid timezone %#q", u.Github, u.Timezone)
+	}
+
+	parts := strings.Split(u.WorkingHours, "-")
+	if len(parts) != 2 {
+		return WorkingHours{}, microerror.Maskf(invalidConfigError, "user %#q: working hours %#q must be given as HH:MM-HH:MM", u.Github, u.WorkingHours)
+	}
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return WorkingHours{}, microerror.Maskf(invalidConfigError, "user %#q: working hours %#q must be given as HH:MM-HH:MM", u.Github, u.WorkingHours)
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return WorkingHours{}, microerror.Maskf(invalidConfigError, "user %#q: working hours %#q must be given as HH:MM-HH:MM", u.Github, u.WorkingHours)
+	}
+	if end.hour*60+end.minute <= start.hour*60+start.minute {
+		return WorkingHours{}, microerror.Maskf(invalidConfigError, "user %#q: working hours %#q must end after they start", u.Github, u.WorkingHours)
+	}
+
+	days := u.WorkingDays
+	if len(days) == 0 {
+		days = defaultWorkingDays
+	}
+	w := WorkingHours{
+		location: location,
+		days:     map[time.Weekday]bool{},
+		start:    start,
+		end:      end,
+	}
+	for _, d := range days {
+		day, ok := weekdays[strings.ToLower(d)]
+		if !ok {
+			return WorkingHours{}, microerror.Maskf(invalidConfigError, "user %#q: invalid working day %#q", u.Github, d)
+		}
+		w.days[day] = true
+	}
+
+	return w, nil
+}
+
+func parseClock(s string) (clock, error) {
+	t, err := time.Parse(clockFormat, strings.TrimSpace(s))
+	if err != nil {
+		return clock{}, microerror.Mask(err)
+	}
+
+	return clock{hour: t.Hour(), minute: t.Minute()}, nil
+}
@@ -0,0 +1,156 @@
+// Package dryrun provides an HTTP transport recording requests instead of
+// sending them, so that decisions can be simulated without changing anything
+// in the on-call backend.
+package dryrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/secret"
+)
+
+const (
+	redacted = "<redacted>"
+)
+
+// Request is a request recorded by the transport. Secret URLs, e.g. of Slack
+// incoming webhooks, are reduced to their host, see secret.WithURL.
+type Request struct {
+	Method string            `json:"method"`
+	URL    string            `json:"url"`
+	Header map[string]string `json:"header,omitempty"`
+	// Body is the request body. It is kept as raw JSON if it is valid JSON.
+	Body interface{} `json:"body,omitempty"`
+	// Sent tells whether the request was actually sent, which is only the
+	// case for reads when LiveReads is enabled.
+	Sent bool `json:"sent"`
+}
+
+type Config struct {
+	// Logger optionally logs the requests that are not sent.
+	Logger micrologger.Logger
+	// Transport sends requests that are actually sent. It defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// Limit is the number of recorded requests kept. Older requests are
+	// dropped. All requests are kept when it is zero.
+	Limit int
+	// LiveReads enables sending GET requests, so that decisions depending on
+	// the current state of the backend or GitHub can be simulated. All other
+	// requests are never sent.
+	LiveReads bool
+}
+
+// Transport is an http.RoundTripper recording all requests. Requests that are
+// not sent are answered with an empty JSON object.
+type Transport struct {
+	logger    micrologger.Logger
+	transport http.RoundTripper
+
+	limit     int
+	liveReads bool
+
+	mutex    sync.Mutex
+	requests []Request
+}
+
+func New(c Config) (*Transport, error) {
+	if c.Limit < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Limit must not be negative", c)
+	}
+	if c.Transport == nil {
+		c.Transport = http.DefaultTransport
+	}
+
+	t := &Transport{
+		logger:    c.Logger,
+		transport: c.Transport,
+
+		limit:     c.Limit,
+		liveReads: c.LiveReads,
+	}
+
+	return t, nil
+}
+
+// Requests returns the recorded requests in the order they were made.
+func (t *Transport) Requests() []Request {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return append([]Request{}, t.requests...)
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := Request{
+		Method: req.Method,
+		URL:    secret.RequestURL(req),
+		Sent:   t.liveReads && req.Method == http.MethodGet,
+	}
+
+	for k := range req.Header {
+		if r.Header == nil {
+			r.Header = map[string]string{}
+		}
+		if k == "Authorization" {
+			r.Header[k] = redacted
+		} else {
+			r.Header[k] = req.Header.Get(k)
+		}
+	}
+
+	var body string
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+		body = string(b)
+
+		if json.Valid(b) {
+			r.Body = json.RawMessage(b)
+		} else if len(b) > 0 {
+			r.Body = string(b)
+		}
+	}
+
+	t.mutex.Lock()
+	t.requests = append(t.requests, r)
+	if t.limit > 0 && len(t.requests) > t.limit {
+		t.requests = t.requests[len(t.requests)-t.limit:]
+	}
+	t.mutex.Unlock()
+
+	if r.Sent {
+		return t.transport.RoundTrip(req)
+	}
+
+	if t.logger != nil {
+		t.logger.Log("level", "info", "message", fmt.Sprintf("dry run, not sending %s %s", r.Method, r.URL), "body", body)
+	}
+
+	resp := &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte("{}"))),
+		ContentLength: 2,
+		Request:       req,
+	}
+
+	return resp, nil
+}
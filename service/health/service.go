@@ -0,0 +1,192 @@
+// Package health checks whether the service and its dependencies work, for
+// liveness and readiness probes.
+package health
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	// StatusFailed is the status of failed checks and reports containing
+	// failed checks.
+	StatusFailed = "failed"
+	// StatusOK is the status of passed checks and reports of passed checks
+	// only.
+	StatusOK = "ok"
+)
+
+// Checker verifies a single dependency of the service.
+type Checker interface {
+	// Check returns an error if the dependency does not work.
+	Check() error
+}
+
+// CheckerFunc adapts a function to the Checker interface.
+type CheckerFunc func() error
+
+// Check calls f.
+func (f CheckerFunc) Check() error {
+	return f()
+}
+
+// Report is the result of a set of checks.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Result is the result of a single check.
+type Result struct {
+	Status string `json:"status"`
+	// Error is the reason the check failed, if it did.
+	Error string `json:"error,omitempty"`
+	// Checked is the point in time the check ran.
+	Checked time.Time `json:"checked"`
+}
+
+// Config represents the configuration used to create a health service.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// CacheTTL is how long results are reused, so that frequent probes do not
+	// put load on dependencies.
+	CacheTTL time.Duration
+	// Liveness are the checkers by name whose failure means the service must
+	// be restarted.
+	Liveness map[string]Checker
+	// Readiness are the checkers by name whose failure means the service can
+	// not process webhooks right now.
+	Readiness map[string]Checker
+}
+
+// Service runs checks and caches their results.
+type Service struct {
+	logger micrologger.Logger
+
+	cacheTTL  time.Duration
+	liveness  []string
+	readiness []string
+	checks    map[string]*check
+}
+
+// check is a checker together with its cached result.
+type check struct {
+	checker Checker
+
+	mutex  sync.Mutex
+	result Result
+}
+
+// New creates a new configured health service. Checkers configured for both
+// liveness and readiness under the same name are run once per cache period.
+func New(config Config) (*Service, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.CacheTTL < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.CacheTTL must not be negative", config)
+	}
+
+	s := &Service{
+		logger: config.Logger,
+
+		cacheTTL: config.CacheTTL,
+		checks:   map[string]*check{},
+	}
+
+	for _, checkers := range []map[string]Checker{config.Liveness, config.Readiness} {
+		for name, c := range checkers {
+			if c == nil {
+				return nil, microerror.Maskf(invalidConfigError, "%T checker %#q must not be empty", config, name)
+			}
+			s.checks[name] = &check{checker: c}
+		}
+	}
+	for name := range config.Liveness {
+		s.liveness = append(s.liveness, name)
+	}
+	for name := range config.Readiness {
+		s.readiness = append(s.readiness, name)
+	}
+	sort.Strings(s.liveness)
+	sort.Strings(s.readiness)
+
+	return s, nil
+}
+
+// Liveness runs the liveness checks.
+func (s *Service) Liveness() Report {
+	return s.report(s.liveness)
+}
+
+// Readiness runs the readiness checks.
+func (s *Service) Readiness() Report {
+	return s.report(s.readiness)
+}
+
+// report runs the checks of the given names concurrently, reusing results
+// not older than the cache TTL.
+func (s *Service) report(names []string) Report {
+	results := make([]Result, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = s.run(name)
+		}(i, name)
+	}
+	wg.Wait()
+
+	r := Report{
+		Status: StatusOK,
+		Checks: map[string]Result{},
+	}
+	for i, name := range names {
+		r.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			r.Status = StatusFailed
+		}
+	}
+
+	return r
+}
+
+func (s *Service) run(name string) Result {
+	c := s.checks[name]
+
+	// Concurrent probes wait for a running check and use its result.
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.result.Checked.IsZero() && time.Since(c.result.Checked) < s.cacheTTL {
+		return c.result
+	}
+
+	result := Result{
+		Status:  StatusOK,
+		Checked: time.Now().UTC(),
+	}
+
+	err := c.checker.Check()
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+
+		if c.result.Status != StatusFailed {
+			s.logger.Log("level", "warning", "message", fmt.Sprintf("health check %#q failed", name), "stack", fmt.Sprintf("%#v", err))
+		}
+	}
+
+	c.result = result
+
+	return result
+}
@@ -0,0 +1,27 @@
+package notifier
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	outcomeDropped   = "dropped"
+	outcomeFailed    = "failed"
+	outcomeSucceeded = "succeeded"
+)
+
+var (
+	notificationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "auto_oncall",
+			Subsystem: "notifier",
+			Name:      "notifications_total",
+			Help:      "Number of notifications about assignments by notifier, event type and outcome.",
+		},
+		[]string{"notifier", "event", "outcome"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(notificationsTotal)
+}
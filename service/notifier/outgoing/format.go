@@ -0,0 +1,156 @@
+package outgoing
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/auto-oncall/service/notifier"
+)
+
+const (
+	// FormatJSON posts the event as Document.
+	FormatJSON = "json"
+	// FormatTeams posts the event as Microsoft Teams message with an adaptive
+	// card, as accepted by Teams incoming webhooks and workflows.
+	FormatTeams = "teams"
+
+	timeFormat = "2006-01-02 15:04 MST"
+)
+
+// formatter renders the body posted for an event with the given delivery ID.
+type formatter func(e notifier.Event, id string) ([]byte, error)
+
+var formatters = map[string]formatter{
+	FormatJSON:  formatJSON,
+	FormatTeams: formatTeams,
+}
+
+// Document is the body posted in FormatJSON.
+type Document struct {
+	// ID is the delivery ID, also sent in DeliveryHeader.
+	ID string `json:"id"`
+	notifier.Event
+}
+
+func formatJSON(e notifier.Event, id string) ([]byte, error) {
+	b, err := json.Marshal(Document{ID: id, Event: e})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return b, nil
+}
+
+// titles are the titles of Teams cards by event type.
+var titles = map[string]string{
+	notifier.EventCreated:    "%s is on call",
+	notifier.EventExpired:    "%s is off call, the assignment expired",
+	notifier.EventExtended:   "%s stays on call longer",
+	notifier.EventFailed:     "Putting %s on call failed",
+	notifier.EventHandedOver: "%s took over on-call",
+	notifier.EventRevoked:    "%s is off call, the assignment was revoked",
+}
+
+func formatTeams(e notifier.Event, id string) ([]byte, error) {
+	a := e.Assignment
+
+	title, ok := titles[e.Type]
+	if !ok {
+		title = "%s: " + e.Type
+	}
+
+	facts := []teamsFact{
+		{Title: "Repository", Value: a.Repository},
+		{Title: "Environment", Value: a.Environment},
+		{Title: "Ref", Value: a.Ref},
+		{Title: "Until", Value: a.Expiry.Format(timeFormat)},
+		{Title: "Assignment", Value: a.Name},
+	}
+	if len(e.Previous) > 0 {
+		var logins []string
+		for _, p := range e.Previous {
+			logins = append(logins, p.Assignee())
+		}
+		facts = append(facts, teamsFact{Title: "Previously", Value: strings.Join(logins, ", ")})
+	}
+	if e.Actor != "" {
+		facts = append(facts, teamsFact{Title: "By", Value: fmt.Sprintf("%s (%s)", e.Actor, e.Origin)})
+	}
+	if e.Reason != "" {
+		facts = append(facts, teamsFact{Title: "Reason", Value: e.Reason})
+	}
+
+	card := teamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body: []interface{}{
+			teamsTextBlock{
+				Type:   "TextBlock",
+				Text:   fmt.Sprintf(title, a.Assignee()),
+				Size:   "Medium",
+				Weight: "Bolder",
+				Wrap:   true,
+			},
+			teamsFactSet{
+				Type:  "FactSet",
+				Facts: facts,
+			},
+		},
+	}
+
+	m := teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{
+			{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content:     card,
+			},
+		},
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return b, nil
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsCard struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []interface{} `json:"body"`
+}
+
+type teamsTextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Size   string `json:"size,omitempty"`
+	Weight string `json:"weight,omitempty"`
+	Wrap   bool   `json:"wrap"`
+}
+
+type teamsFactSet struct {
+	Type  string      `json:"type"`
+	Facts []teamsFact `json:"facts"`
+}
+
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
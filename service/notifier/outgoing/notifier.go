@@ -0,0 +1,215 @@
+// Package outgoing implements a notifier posting a document about every
+// event to a configured URL, signed with HMAC, so that other systems can
+// react to changes of assignments.
+package outgoing
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/notifier"
+	"github.com/giantswarm/auto-oncall/service/secret"
+)
+
+const (
+	// Name is the name of outgoing webhook notifiers.
+	Name = "webhook"
+
+	// DeliveryHeader is the header identifying a notification. It stays the
+	// same when a notification is retried.
+	DeliveryHeader = "X-Auto-Oncall-Delivery"
+	// EventHeader is the header carrying the event type.
+	EventHeader = "X-Auto-Oncall-Event"
+	// SignatureHeader is the header carrying the HMAC-SHA256 of the body,
+	// keyed with the secret, hex encoded and prefixed with sha256=.
+	SignatureHeader = "X-Auto-Oncall-Signature"
+
+	signaturePrefix = "sha256="
+)
+
+const (
+	// attempts is the number of times a notification is sent at most.
+	attempts = 5
+	// backoff is the delay before the first retry. It doubles with every
+	// further retry.
+	backoff = time.Second
+)
+
+// Endpoint is a URL notifications are posted to.
+type Endpoint struct {
+	// URL is where notifications are posted to.
+	URL string
+	// Secret optionally signs notifications.
+	Secret string
+	// Format is the format of the posted documents, either FormatJSON,
+	// the default, or FormatTeams.
+	Format string
+	// Events are the event types posted. All events are posted when empty.
+	Events []string
+}
+
+// Config represents the configuration used to create an outgoing webhook
+// notifier.
+type Config struct {
+	HttpClient *http.Client
+	Logger     micrologger.Logger
+
+	Endpoint Endpoint
+}
+
+// Notifier posts events to a single endpoint.
+type Notifier struct {
+	httpClient *http.Client
+	logger     micrologger.Logger
+
+	events map[string]bool
+	format formatter
+	name   string
+	secret []byte
+	url    string
+}
+
+// New creates a new configured outgoing webhook notifier.
+func New(config Config) (*Notifier, error) {
+	if config.HttpClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.HttpClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Endpoint.URL == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Endpoint.URL must not be empty", config)
+	}
+	if config.Endpoint.Format == "" {
+		config.Endpoint.Format = FormatJSON
+	}
+	format, ok := formatters[config.Endpoint.Format]
+	if !ok {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Endpoint.Format must be %#q or %#q, got %#q", config, FormatJSON, FormatTeams, config.Endpoint.Format)
+	}
+
+	n := &Notifier{
+		httpClient: config.HttpClient,
+		logger:     config.Logger,
+
+		format: format,
+		name:   Name,
+		secret: []byte(config.Endpoint.Secret),
+		url:    config.Endpoint.URL,
+	}
+	if config.Endpoint.Format != FormatJSON {
+		n.name = config.Endpoint.Format
+	}
+
+	if len(config.Endpoint.Events) > 0 {
+		n.events = map[string]bool{}
+		for _, e := range config.Endpoint.Events {
+			n.events[e] = true
+		}
+	}
+
+	return n, nil
+}
+
+// Name returns Name, or the format for formats other than FormatJSON.
+func (n *Notifier) Name() string {
+	return n.name
+}
+
+// Notify posts the given event, retrying with exponential backoff on
+// network errors and 5xx or 429 responses.
+func (n *Notifier) Notify(ctx context.Context, e notifier.Event) error {
+	if n.events != nil && !n.events[e.Type] {
+		return nil
+	}
+
+	id := deliveryID()
+	body, err := n.format(e, id)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	delay := backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, e, id, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == attempts {
+			return microerror.Mask(err)
+		}
+
+		n.logger.Log("level", "warning", "message", fmt.Sprintf("sending %s notification failed, retrying in %s", e.Type, delay), "delivery", id, "attempt", attempt, "stack", fmt.Sprintf("%#v", err))
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return microerror.Mask(ctx.Err())
+		}
+		delay *= 2
+	}
+}
+
+// post sends the given body once. It tells whether a failure is worth
+// retrying.
+func (n *Notifier) post(ctx context.Context, e notifier.Event, id string, body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+	// The URL may carry a token, e.g. of Teams incoming webhooks.
+	req = req.WithContext(secret.WithURL(ctx))
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, id)
+	req.Header.Set(EventHeader, e.Type)
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, signaturePrefix+Sign(body, n.secret))
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return true, microerror.Mask(secret.URLError(req, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, microerror.Maskf(executionFailedError, "expected 2xx, got %d: %s", resp.StatusCode, b)
+	}
+
+	return false, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the given body, keyed with the
+// given secret. Receivers compare it with the signature header, without its
+// sha256= prefix, to verify notifications.
+func Sign(body, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func deliveryID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(b)
+}
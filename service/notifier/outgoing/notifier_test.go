@@ -0,0 +1,229 @@
+package outgoing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/notifier"
+)
+
+// delivery is a notification received by the endpoint stub.
+type delivery struct {
+	ID        string
+	Event     string
+	Signature string
+	Body      []byte
+}
+
+// stub is an endpoint answering with the given status codes in turn, the
+// last one repeatedly, and recording the notifications posted to it.
+type stub struct {
+	*httptest.Server
+
+	mutex      sync.Mutex
+	deliveries []delivery
+}
+
+func newStub(t *testing.T, statusCodes ...int) *stub {
+	s := &stub{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request failed: %s", err)
+		}
+
+		s.mutex.Lock()
+		s.deliveries = append(s.deliveries, delivery{
+			ID:        r.Header.Get(DeliveryHeader),
+			Event:     r.Header.Get(EventHeader),
+			Signature: r.Header.Get(SignatureHeader),
+			Body:      b,
+		})
+		i := len(s.deliveries) - 1
+		s.mutex.Unlock()
+
+		if i >= len(statusCodes) {
+			i = len(statusCodes) - 1
+		}
+		w.WriteHeader(statusCodes[i])
+	}))
+
+	return s
+}
+
+func (s *stub) Deliveries() []delivery {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]delivery{}, s.deliveries...)
+}
+
+func Test_Sign(t *testing.T) {
+	signature := Sign([]byte("hello"), []byte("key"))
+	expected := "9307b3b915efb5171ff14d8cb55fbcc798c6c0ef1456d66ded1a6aa723a58b7b"
+	if signature != expected {
+		t.Fatalf("expected %#q, got %#q", expected, signature)
+	}
+}
+
+func Test_Notifier_Notify(t *testing.T) {
+	event := notifier.Event{
+		Type: notifier.EventCreated,
+		Assignment: assignment.Assignment{
+			Name:        "aws-operator-anteater",
+			GithubLogin: "johndoe",
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		endpoint           Endpoint
+		statusCodes        []int
+		expectedErr        bool
+		expectedDeliveries int
+	}{
+		{
+			name:               "case 0: signed notification",
+			endpoint:           Endpoint{Secret: "secret"},
+			statusCodes:        []int{http.StatusOK},
+			expectedDeliveries: 1,
+		},
+		{
+			name:               "case 1: unsigned notification",
+			statusCodes:        []int{http.StatusNoContent},
+			expectedDeliveries: 1,
+		},
+		{
+			name:               "case 2: retried after server error",
+			endpoint:           Endpoint{Secret: "secret"},
+			statusCodes:        []int{http.StatusServiceUnavailable, http.StatusOK},
+			expectedDeliveries: 2,
+		},
+		{
+			name:               "case 3: not retried after client error",
+			statusCodes:        []int{http.StatusBadRequest, http.StatusOK},
+			expectedErr:        true,
+			expectedDeliveries: 1,
+		},
+		{
+			name:               "case 4: events not posted to the endpoint",
+			endpoint:           Endpoint{Events: []string{notifier.EventExpired}},
+			statusCodes:        []int{http.StatusOK},
+			expectedDeliveries: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newStub(t, tc.statusCodes...)
+			defer s.Close()
+
+			logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := Config{
+				HttpClient: s.Client(),
+				Logger:     logger,
+
+				Endpoint: tc.endpoint,
+			}
+			c.Endpoint.URL = s.URL + "/hook?token=abc"
+
+			n, err := New(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = n.Notify(context.Background(), event)
+			if tc.expectedErr && !IsExecutionFailed(err) {
+				t.Fatalf("expected execution failed error, got %#v", err)
+			}
+			if !tc.expectedErr && err != nil {
+				t.Fatalf("expected no error, got %#v", err)
+			}
+
+			deliveries := s.Deliveries()
+			if len(deliveries) != tc.expectedDeliveries {
+				t.Fatalf("expected %d deliveries, got %d", tc.expectedDeliveries, len(deliveries))
+			}
+
+			for _, d := range deliveries {
+				if d.ID == "" || d.ID != deliveries[0].ID {
+					t.Fatalf("expected the same delivery ID on every attempt, got %#q and %#q", deliveries[0].ID, d.ID)
+				}
+				if d.Event != event.Type {
+					t.Fatalf("expected event %#q, got %#q", event.Type, d.Event)
+				}
+
+				var document Document
+				err := json.Unmarshal(d.Body, &document)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if document.ID != d.ID || document.Event.Assignment.Name != event.Assignment.Name {
+					t.Fatalf("expected document of delivery %#q for assignment %#q, got %#v", d.ID, event.Assignment.Name, document)
+				}
+
+				expected := ""
+				if tc.endpoint.Secret != "" {
+					expected = signaturePrefix + Sign(d.Body, []byte(tc.endpoint.Secret))
+				}
+				if d.Signature != expected {
+					t.Fatalf("expected signature %#q, got %#q", expected, d.Signature)
+				}
+			}
+		})
+	}
+}
+
+func Test_Notifier_Notify_SecretURL(t *testing.T) {
+	var log bytes.Buffer
+	logger, err := micrologger.New(micrologger.Config{IOWriter: &log})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newStub(t, http.StatusOK)
+	url := s.URL + "/hook?token=abc"
+	s.Close()
+
+	c := Config{
+		HttpClient: s.Client(),
+		Logger:     logger,
+
+		Endpoint: Endpoint{URL: url},
+	}
+
+	n, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The failed attempt is logged before waiting for the retry, which is
+	// given up on immediately.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = n.Notify(ctx, notifier.Event{Type: notifier.EventCreated})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(log.String(), "retrying") {
+		t.Fatalf("expected failed attempt to be logged, got %#q", log.String())
+	}
+	if strings.Contains(log.String(), "token=abc") || strings.Contains(err.Error(), "token=abc") {
+		t.Fatalf("expected logs and error without secret URL, got %#q and %#q", log.String(), err.Error())
+	}
+}
@@ -0,0 +1,165 @@
+// Package notifier tells people about changes of assignments, e.g. the
+// deployer that they are on call now, through chat and other integrations.
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/tracing"
+)
+
+const (
+	// EventCreated is the event of an assignment created without taking over
+	// from other assignments.
+	EventCreated = "created"
+	// EventExpired is the event of an assignment deleted because it expired.
+	EventExpired = "expired"
+	// EventExtended is the event of an assignment whose expiry was moved,
+	// either by a repeated deployment or through the admin API.
+	EventExtended = "extended"
+	// EventFailed is the event of an assignment failing to be created or
+	// extended. The reason is given with the event.
+	EventFailed = "failed"
+	// EventHandedOver is the event of an assignment created taking over from
+	// previous assignments, which are given with the event.
+	EventHandedOver = "handed_over"
+	// EventRevoked is the event of an assignment deleted through the admin
+	// API.
+	EventRevoked = "revoked"
+)
+
+const (
+	// queueSize is the number of notifications waiting to be sent by a
+	// notifier at most. Further notifications are dropped.
+	queueSize = 100
+)
+
+// Event is a change of an assignment notifiers are told about.
+type Event struct {
+	// Type is what happened, e.g. EventCreated.
+	Type string `json:"type"`
+	// Time is the point in time the change was made.
+	Time time.Time `json:"time"`
+	// Assignment is the changed assignment.
+	Assignment assignment.Assignment `json:"assignment"`
+	// Previous are the assignments handed over from with EventHandedOver.
+	Previous []assignment.Assignment `json:"previous,omitempty"`
+	// Origin and Actor tell who caused the change, as in the audit log.
+	Origin string `json:"origin,omitempty"`
+	Actor  string `json:"actor,omitempty"`
+	// Reason tells why the change failed with EventFailed.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Notifier sends notifications about events to a single integration.
+type Notifier interface {
+	// Name identifies the notifier in logs and metrics.
+	Name() string
+	// Notify sends the notifications about the given event. Events a
+	// notifier is not interested in are ignored.
+	Notify(ctx context.Context, e Event) error
+}
+
+// Config represents the configuration used to create a notifier service.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Notifiers are told about every event. There may be none.
+	Notifiers []Notifier
+}
+
+// Service dispatches events to the configured notifiers in the background, so
+// that slow or failing integrations do not hold back assignments. Each
+// notifier has a queue of its own, so that they do not hold back each other
+// either.
+type Service struct {
+	logger micrologger.Logger
+
+	notifiers []Notifier
+	queues    []chan queued
+}
+
+// queued is an event waiting to be sent, together with the context carrying
+// its trace.
+type queued struct {
+	ctx   context.Context
+	event Event
+}
+
+// New creates a new configured notifier service.
+func New(config Config) (*Service, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	s := &Service{
+		logger: config.Logger,
+
+		notifiers: config.Notifiers,
+	}
+	for range config.Notifiers {
+		s.queues = append(s.queues, make(chan queued, queueSize))
+	}
+
+	return s, nil
+}
+
+// Notify schedules sending notifications about the given event. The time of
+// the event is set if it is not given.
+func (s *Service) Notify(ctx context.Context, e Event) {
+	if len(s.notifiers) == 0 {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	q := queued{ctx: tracing.Detach(ctx), event: e}
+	for i, n := range s.notifiers {
+		select {
+		case s.queues[i] <- q:
+		default:
+			notificationsTotal.WithLabelValues(n.Name(), e.Type, outcomeDropped).Inc()
+			s.logger.Log("level", "error", "message", fmt.Sprintf("dropping %s notification for %s, %d notifications waiting", e.Type, n.Name(), queueSize), "assignment", e.Assignment.Name)
+		}
+	}
+}
+
+// Boot sends queued notifications, one after another per notifier. It
+// blocks forever.
+func (s *Service) Boot() {
+	for i := range s.notifiers {
+		go s.work(s.notifiers[i], s.queues[i])
+	}
+
+	select {}
+}
+
+func (s *Service) work(n Notifier, queue chan queued) {
+	for q := range queue {
+		s.notify(q.ctx, n, q.event)
+	}
+}
+
+func (s *Service) notify(ctx context.Context, n Notifier, e Event) {
+	ctx, span := tracing.Start(ctx, "notify")
+	span.SetAttribute("notifier", n.Name())
+	span.SetAttribute("event", e.Type)
+
+	err := n.Notify(ctx, e)
+	span.End(err)
+	if err != nil {
+		notificationsTotal.WithLabelValues(n.Name(), e.Type, outcomeFailed).Inc()
+		s.logger.Log("level", "error", "message", fmt.Sprintf("sending %s notification with %s failed", e.Type, n.Name()), "assignment", e.Assignment.Name, "stack", fmt.Sprintf("%#v", err))
+		return
+	}
+
+	notificationsTotal.WithLabelValues(n.Name(), e.Type, outcomeSucceeded).Inc()
+}
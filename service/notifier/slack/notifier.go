@@ -0,0 +1,307 @@
+// Package slack implements a notifier sending direct messages to deployers
+// and summaries to an ops channel in Slack.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/directory"
+	"github.com/giantswarm/auto-oncall/service/notifier"
+	"github.com/giantswarm/auto-oncall/service/secret"
+)
+
+const (
+	// Name is the name of the Slack notifier.
+	Name = "slack"
+
+	// DefaultAPIURL is the base URL of the Slack Web API.
+	DefaultAPIURL = "https://slack.com/api"
+
+	postMessageMethod = "chat.postMessage"
+	timeFormat        = "2006-01-02 15:04 MST"
+)
+
+// DefaultTemplates are the templates used for event types without a
+// configured template.
+var DefaultTemplates = Templates{
+	Direct: map[string]string{
+		notifier.EventCreated:    "You are on call for *{{ .Assignment.Repository }}* on *{{ .Assignment.Environment }}* until {{ time .Assignment.Expiry }}, because you deployed `{{ .Assignment.Ref }}`.",
+		notifier.EventExpired:    "You are not on call for *{{ .Assignment.Repository }}* on *{{ .Assignment.Environment }}* anymore, your assignment expired.",
+		notifier.EventHandedOver: "You are on call for *{{ .Assignment.Repository }}* on *{{ .Assignment.Environment }}* until {{ time .Assignment.Expiry }}, taking over from {{ range $i, $p := .Previous }}{{ if $i }}, {{ end }}{{ mention $p }}{{ end }}, because you deployed `{{ .Assignment.Ref }}`.",
+	},
+	Channel: map[string]string{
+		notifier.EventCreated:    "{{ mention .Assignment }} is on call for *{{ .Assignment.Repository }}* on *{{ .Assignment.Environment }}* until {{ time .Assignment.Expiry }} after deploying `{{ .Assignment.Ref }}`.",
+		notifier.EventExpired:    "{{ mention .Assignment }} is off call for *{{ .Assignment.Repository }}* on *{{ .Assignment.Environment }}*, the assignment expired.",
+		notifier.EventHandedOver: "{{ mention .Assignment }} took over on-call for *{{ .Assignment.Repository }}* on *{{ .Assignment.Environment }}* from {{ range $i, $p := .Previous }}{{ if $i }}, {{ end }}{{ mention $p }}{{ end }} until {{ time .Assignment.Expiry }} after deploying `{{ .Assignment.Ref }}`.",
+	},
+}
+
+// Templates are the text/templates of messages by event type. They are
+// rendered with the notifier.Event. The functions mention, rendering a
+// Slack mention of the deployer of an assignment, and time, formatting a
+// point in time, are available. An empty template disables messages for its
+// event type.
+type Templates struct {
+	// Direct are the templates of direct messages to the deployer.
+	Direct map[string]string
+	// Channel are the templates of messages to the ops channel.
+	Channel map[string]string
+}
+
+// Config represents the configuration used to create a Slack notifier.
+type Config struct {
+	// Directory provides the Slack member IDs of deployers.
+	Directory  *directory.Directory
+	HttpClient *http.Client
+	Logger     micrologger.Logger
+
+	// APIURL is the base URL of the Slack Web API. It defaults to
+	// DefaultAPIURL and can point to a local stub for testing.
+	APIURL string
+	// Channel is the ID or name of the ops channel. It is used with Token.
+	Channel   string
+	Templates Templates
+	// Token is the bot token used to send direct messages and, with
+	// Channel, messages to the ops channel.
+	Token string
+	// WebhookURL is the incoming webhook messages to the ops channel are
+	// sent to. It takes precedence over Channel.
+	WebhookURL string
+}
+
+// Notifier sends messages about assignments to Slack.
+type Notifier struct {
+	directory  *directory.Directory
+	httpClient *http.Client
+	logger     micrologger.Logger
+
+	apiURL     string
+	channel    string
+	direct     map[string]*template.Template
+	public     map[string]*template.Template
+	token      string
+	webhookURL string
+}
+
+// New creates a new configured Slack notifier.
+func New(config Config) (*Notifier, error) {
+	if config.Directory == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Directory must not be empty", config)
+	}
+	if config.HttpClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.HttpClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Token == "" && config.WebhookURL == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Token or %T.WebhookURL must not be empty", config, config)
+	}
+	if config.APIURL == "" {
+		config.APIURL = DefaultAPIURL
+	}
+
+	n := &Notifier{
+		directory:  config.Directory,
+		httpClient: config.HttpClient,
+		logger:     config.Logger,
+
+		apiURL:     strings.TrimSuffix(config.APIURL, "/"),
+		channel:    config.Channel,
+		token:      config.Token,
+		webhookURL: config.WebhookURL,
+	}
+
+	var err error
+	n.direct, err = n.parse(DefaultTemplates.Direct, config.Templates.Direct)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	n.public, err = n.parse(DefaultTemplates.Channel, config.Templates.Channel)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return n, nil
+}
+
+func (n *Notifier) Name() string {
+	return Name
+}
+
+// Notify sends a direct message to the deployer, if they are in the
+// directory with a Slack member ID and a token is configured, and a message
+// to the ops channel, if one is configured.
+func (n *Notifier) Notify(ctx context.Context, e notifier.Event) error {
+	var failed []string
+
+	if t, ok := n.direct[e.Type]; ok && n.token != "" && e.Assignment.GithubLogin != "" {
+		u, ok := n.directory.Get(e.Assignment.GithubLogin)
+		if ok && u.Slack != "" {
+			err := n.send(ctx, t, e, u.Slack)
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("direct message: %s", err.Error()))
+			}
+		} else {
+			n.logger.Log("level", "debug", "message", "not sending direct message, no Slack member ID configured", "user", e.Assignment.GithubLogin)
+		}
+	}
+
+	if t, ok := n.public[e.Type]; ok && (n.webhookURL != "" || n.token != "" && n.channel != "") {
+		channel := n.channel
+		if n.webhookURL != "" {
+			channel = ""
+		}
+		err := n.send(ctx, t, e, channel)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("channel message: %s", err.Error()))
+		}
+	}
+
+	if len(failed) > 0 {
+		return microerror.Maskf(executionFailedError, "%s", strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+// send renders the given template and posts the message to the given
+// channel using the Web API, or to the incoming webhook without channel.
+func (n *Notifier) send(ctx context.Context, t *template.Template, e notifier.Event, channel string) error {
+	var buf bytes.Buffer
+	err := t.Execute(&buf, e)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	m := message{
+		Text: strings.TrimSpace(buf.String()),
+	}
+
+	url := n.webhookURL
+	if channel != "" {
+		url = fmt.Sprintf("%s/%s", n.apiURL, postMessageMethod)
+		m.Channel = channel
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if m.Channel == "" {
+		// Incoming webhook URLs are secrets themselves.
+		ctx = secret.WithURL(ctx)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if m.Channel != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", n.token))
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return microerror.Mask(secret.URLError(req, err))
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return microerror.Maskf(executionFailedError, "expected 200, got %d: %s", resp.StatusCode, body)
+	}
+
+	// Incoming webhooks answer with plain text, the Web API with a JSON
+	// document telling whether the call succeeded.
+	if m.Channel != "" {
+		var r response
+		err = json.Unmarshal(body, &r)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if !r.OK {
+			return microerror.Maskf(executionFailedError, "%s: %s", postMessageMethod, r.Error)
+		}
+	}
+
+	return nil
+}
+
+// parse parses the configured templates on top of the given defaults. Empty
+// configured templates remove the default.
+func (n *Notifier) parse(defaults, configured map[string]string) (map[string]*template.Template, error) {
+	texts := map[string]string{}
+	for k, v := range defaults {
+		texts[k] = v
+	}
+	for k, v := range configured {
+		if v == "" {
+			delete(texts, k)
+			continue
+		}
+		texts[k] = v
+	}
+
+	funcs := template.FuncMap{
+		"mention": n.mention,
+		"time": func(t time.Time) string {
+			return t.Format(timeFormat)
+		},
+	}
+
+	templates := map[string]*template.Template{}
+	for k, v := range texts {
+		t, err := template.New(k).Funcs(funcs).Parse(v)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "template %#q: %s", k, err.Error())
+		}
+		err = t.Execute(ioutil.Discard, notifier.Event{})
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "template %#q: %s", k, err.Error())
+		}
+
+		templates[k] = t
+	}
+
+	return templates, nil
+}
+
+// mention returns a Slack mention of the deployer of the given assignment, or
+// who is on call otherwise if they have no Slack member ID configured or a
+// schedule is on call instead.
+func (n *Notifier) mention(a assignment.Assignment) string {
+	u, ok := n.directory.Get(a.GithubLogin)
+	if !ok || u.Slack == "" {
+		return a.Assignee()
+	}
+
+	return fmt.Sprintf("<@%s>", u.Slack)
+}
+
+type message struct {
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text"`
+}
+
+type response struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,306 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/directory"
+	"github.com/giantswarm/auto-oncall/service/notifier"
+)
+
+// request is a request received by the Slack stub.
+type request struct {
+	Path          string
+	Authorization string
+	Channel       string
+	Text          string
+}
+
+// stub is a Slack stub recording the messages posted to it. The Web API
+// answers with the given response.
+type stub struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	requests []request
+}
+
+func newStub(t *testing.T, response string) *stub {
+	s := &stub{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request failed: %s", err)
+		}
+		var m message
+		err = json.Unmarshal(b, &m)
+		if err != nil {
+			t.Errorf("decoding request failed: %s", err)
+		}
+
+		s.mutex.Lock()
+		s.requests = append(s.requests, request{
+			Path:          r.URL.Path,
+			Authorization: r.Header.Get("Authorization"),
+			Channel:       m.Channel,
+			Text:          m.Text,
+		})
+		s.mutex.Unlock()
+
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			w.Write([]byte(response))
+		} else {
+			w.Write([]byte("ok"))
+		}
+	}))
+
+	return s
+}
+
+func (s *stub) Requests() []request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]request{}, s.requests...)
+}
+
+func Test_Notifier_Notify(t *testing.T) {
+	event := notifier.Event{
+		Type: notifier.EventCreated,
+		Time: time.Date(2020, 5, 4, 10, 0, 0, 0, time.UTC),
+		Assignment: assignment.Assignment{
+			GithubLogin: "johndoe",
+			Repository:  "aws-operator",
+			Environment: "anteater",
+			Ref:         "v1.2.3",
+			Expiry:      time.Date(2020, 5, 4, 14, 0, 0, 0, time.UTC),
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		config       func(s *stub) Config
+		response     string
+		event        notifier.Event
+		expectedErr  string
+		expectedReqs []request
+	}{
+		{
+			name: "case 0: direct and channel message through the Web API",
+			config: func(s *stub) Config {
+				return Config{
+					APIURL:  s.URL + "/api",
+					Channel: "ops",
+					Token:   "xoxb-token",
+				}
+			},
+			response: `{"ok": true}`,
+			event:    event,
+			expectedReqs: []request{
+				{
+					Path:          "/api/chat.postMessage",
+					Authorization: "Bearer xoxb-token",
+					Channel:       "U0123ABCD",
+					Text:          "You are on call for *aws-operator* on *anteater* until 2020-05-04 14:00 UTC, because you deployed `v1.2.3`.",
+				},
+				{
+					Path:          "/api/chat.postMessage",
+					Authorization: "Bearer xoxb-token",
+					Channel:       "ops",
+					Text:          "<@U0123ABCD> is on call for *aws-operator* on *anteater* until 2020-05-04 14:00 UTC after deploying `v1.2.3`.",
+				},
+			},
+		},
+		{
+			name: "case 1: channel message through the incoming webhook",
+			config: func(s *stub) Config {
+				return Config{
+					APIURL:     s.URL + "/api",
+					Channel:    "ops",
+					Token:      "xoxb-token",
+					WebhookURL: s.URL + "/services/T000/B000/XXX",
+				}
+			},
+			response: `{"ok": true}`,
+			event:    event,
+			expectedReqs: []request{
+				{
+					Path:          "/api/chat.postMessage",
+					Authorization: "Bearer xoxb-token",
+					Channel:       "U0123ABCD",
+					Text:          "You are on call for *aws-operator* on *anteater* until 2020-05-04 14:00 UTC, because you deployed `v1.2.3`.",
+				},
+				{
+					Path: "/services/T000/B000/XXX",
+					Text: "<@U0123ABCD> is on call for *aws-operator* on *anteater* until 2020-05-04 14:00 UTC after deploying `v1.2.3`.",
+				},
+			},
+		},
+		{
+			name: "case 2: no direct message without token",
+			config: func(s *stub) Config {
+				return Config{
+					APIURL:     s.URL + "/api",
+					WebhookURL: s.URL + "/services/T000/B000/XXX",
+				}
+			},
+			response: `{"ok": true}`,
+			event:    event,
+			expectedReqs: []request{
+				{
+					Path: "/services/T000/B000/XXX",
+					Text: "<@U0123ABCD> is on call for *aws-operator* on *anteater* until 2020-05-04 14:00 UTC after deploying `v1.2.3`.",
+				},
+			},
+		},
+		{
+			name: "case 3: failed Web API call",
+			config: func(s *stub) Config {
+				return Config{
+					APIURL:  s.URL + "/api",
+					Channel: "ops",
+					Token:   "xoxb-token",
+				}
+			},
+			response:    `{"ok": false, "error": "channel_not_found"}`,
+			event:       event,
+			expectedErr: "channel_not_found",
+			expectedReqs: []request{
+				{
+					Path:          "/api/chat.postMessage",
+					Authorization: "Bearer xoxb-token",
+					Channel:       "U0123ABCD",
+					Text:          "You are on call for *aws-operator* on *anteater* until 2020-05-04 14:00 UTC, because you deployed `v1.2.3`.",
+				},
+				{
+					Path:          "/api/chat.postMessage",
+					Authorization: "Bearer xoxb-token",
+					Channel:       "ops",
+					Text:          "<@U0123ABCD> is on call for *aws-operator* on *anteater* until 2020-05-04 14:00 UTC after deploying `v1.2.3`.",
+				},
+			},
+		},
+		{
+			name: "case 4: overridden and disabled templates",
+			config: func(s *stub) Config {
+				return Config{
+					APIURL:  s.URL + "/api",
+					Channel: "ops",
+					Templates: Templates{
+						Direct: map[string]string{
+							notifier.EventCreated: "Deployed {{ .Assignment.Ref }} of {{ .Assignment.Repository }}, {{ mention .Assignment }}.",
+						},
+						Channel: map[string]string{
+							notifier.EventCreated: "",
+						},
+					},
+					Token: "xoxb-token",
+				}
+			},
+			response: `{"ok": true}`,
+			event:    event,
+			expectedReqs: []request{
+				{
+					Path:          "/api/chat.postMessage",
+					Authorization: "Bearer xoxb-token",
+					Channel:       "U0123ABCD",
+					Text:          "Deployed v1.2.3 of aws-operator, <@U0123ABCD>.",
+				},
+			},
+		},
+		{
+			name: "case 5: no messages for event types without template",
+			config: func(s *stub) Config {
+				return Config{
+					APIURL:  s.URL + "/api",
+					Channel: "ops",
+					Token:   "xoxb-token",
+				}
+			},
+			response: `{"ok": true}`,
+			event: notifier.Event{
+				Type:       notifier.EventExtended,
+				Assignment: event.Assignment,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newStub(t, tc.response)
+			defer s.Close()
+
+			logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err := directory.New([]directory.User{{Github: "johndoe", Slack: "U0123ABCD"}}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := tc.config(s)
+			c.Directory = d
+			c.HttpClient = s.Client()
+			c.Logger = logger
+
+			n, err := New(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = n.Notify(context.Background(), tc.event)
+			if tc.expectedErr == "" && err != nil {
+				t.Fatalf("expected no error, got %#v", err)
+			}
+			if tc.expectedErr != "" && (err == nil || !strings.Contains(err.Error(), tc.expectedErr)) {
+				t.Fatalf("expected error containing %#q, got %#v", tc.expectedErr, err)
+			}
+
+			requests := s.Requests()
+			if len(requests) != len(tc.expectedReqs) || len(requests) > 0 && !reflect.DeepEqual(requests, tc.expectedReqs) {
+				t.Fatalf("expected requests %#v, got %#v", tc.expectedReqs, requests)
+			}
+		})
+	}
+}
+
+func Test_New_InvalidTemplate(t *testing.T) {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := directory.New(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Config{
+		Directory:  d,
+		HttpClient: http.DefaultClient,
+		Logger:     logger,
+
+		Templates: Templates{
+			Direct: map[string]string{
+				notifier.EventCreated: "{{ .Assignment.Unknown }}",
+			},
+		},
+		Token: "xoxb-token",
+	}
+
+	_, err = New(c)
+	if !IsInvalidConfig(err) {
+		t.Fatalf("expected invalid config error, got %#v", err)
+	}
+}
@@ -0,0 +1,282 @@
+// Package policy decides whether and how deployers are put on call beyond the
+// user mapping, based on their availability and their working hours as
+// configured in the user directory.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/availability"
+	"github.com/giantswarm/auto-oncall/service/directory"
+)
+
+const (
+	// ActionBackup puts the backup of absent deployers on call instead.
+	ActionBackup = "backup"
+	// ActionSchedule puts the on-call schedule of the team of absent
+	// deployers without available backup on call instead. Their assignment
+	// is skipped with ActionSkip when the team has no schedule or the
+	// provider cannot put schedules on call.
+	ActionSchedule = "schedule"
+
+	// ActionCoresponder pages the regular on-call schedule together with
+	// deployers whose assignment reaches outside of their working hours.
+	ActionCoresponder = "coresponder"
+	// ActionNone makes assignments regardless of working hours.
+	ActionNone = "none"
+	// ActionShorten ends assignments at the end of the working hours of the
+	// deployer. Deployments outside of working hours are skipped.
+	ActionShorten = "shorten"
+	// ActionSkip skips assignments of deployments outside of the working
+	// hours of the deployer, or of absent deployers without available backup
+	// and schedule.
+	ActionSkip = "skip"
+
+	// ResolutionBackup is the resolution of assignments handed to the backup
+	// of the deployer.
+	ResolutionBackup = "backup"
+	// ResolutionSchedule is the resolution of assignments handed to the
+	// schedule of the team of the deployer.
+	ResolutionSchedule = "schedule"
+)
+
+// Config represents the configuration used to create a policy.
+type Config struct {
+	// Directory provides the working hours, calendars, teams and backups of
+	// deployers.
+	Directory *directory.Directory
+	Logger    micrologger.Logger
+
+	// OutsideWorkingHours is the action taken on assignments reaching outside
+	// of the working hours of the deployer. It is one of ActionCoresponder,
+	// ActionNone, the default, ActionShorten or ActionSkip.
+	OutsideWorkingHours string
+	// Schedule is the regular on-call schedule paged with ActionCoresponder.
+	Schedule string
+	// Schedules tells whether the provider can put schedules on call instead
+	// of users, which override shifts cannot. Assignments of absent deployers
+	// without available backup are skipped otherwise.
+	Schedules bool
+	// Sources tell whether deployers are available. Availability is not
+	// checked without sources.
+	Sources []availability.Source
+	// Users maps GitHub logins to users of the provider. Backups must be
+	// mapped.
+	Users map[string]string
+}
+
+// Policy applies the availability and working hours of deployers to their
+// assignments.
+type Policy struct {
+	directory *directory.Directory
+	logger    micrologger.Logger
+
+	action    string
+	schedule  string
+	schedules bool
+	sources   []availability.Source
+	users     map[string]string
+}
+
+// Decision is what the policy decided for an assignment.
+type Decision struct {
+	// Action is the action taken on the assignment, ActionNone when it is
+	// made as is.
+	Action string `json:"action"`
+	// Reason explains the decision.
+	Reason string `json:"reason"`
+}
+
+// New creates a new configured policy.
+func New(config Config) (*Policy, error) {
+	if config.Directory == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Directory must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.OutsideWorkingHours == "" {
+		config.OutsideWorkingHours = ActionNone
+	}
+	switch config.OutsideWorkingHours {
+	case ActionCoresponder:
+		if config.Schedule == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Schedule must not be empty with %#q", config, ActionCoresponder)
+		}
+	case ActionNone, ActionShorten, ActionSkip:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "%T.OutsideWorkingHours must be %#q, %#q, %#q or %#q, got %#q", config, ActionCoresponder, ActionNone, ActionShorten, ActionSkip, config.OutsideWorkingHours)
+	}
+
+	for _, u := range config.Directory.Users() {
+		if _, ok := config.Users[u.Backup]; u.Backup != "" && !ok {
+			return nil, microerror.Maskf(invalidConfigError, "backup %#q of user %#q must be mapped to a user", u.Backup, u.Github)
+		}
+	}
+
+	p := &Policy{
+		directory: config.Directory,
+		logger:    config.Logger,
+
+		action:    config.OutsideWorkingHours,
+		schedule:  config.Schedule,
+		schedules: config.Schedules,
+		sources:   config.Sources,
+		users:     config.Users,
+	}
+
+	return p, nil
+}
+
+// Apply decides on the given assignment of a deployment made at the given
+// point in time and changes its user, expiry or responders accordingly. The
+// assignment of an absent deployer is handed to their backup. When the backup
+// is absent too, it is handed to the schedule of the team of the deployer,
+// or skipped when there is none. Working hours are applied to the user on
+// call then. It returns the decisions made, none when the policy does not
+// apply.
+func (p *Policy) Apply(ctx context.Context, a *assignment.Assignment, now time.Time) []Decision {
+	var decisions []Decision
+
+	absence := p.absence(ctx, a.GithubLogin, now)
+	if absence != "" {
+		d, ok := p.replace(ctx, a, absence, now)
+		decisions = append(decisions, d)
+		if !ok {
+			return decisions
+		}
+	}
+
+	d, ok := p.applyWorkingHours(a, now)
+	if ok {
+		decisions = append(decisions, d)
+	}
+
+	return decisions
+}
+
+// replace hands the given assignment of an absent deployer to their backup.
+// Without available backup it hands it to the schedule of the team of the
+// deployer, so that the deployer is not paged, or skips the assignment when
+// there is none. It returns false when there is no available backup, as
+// working hours do not apply then.
+func (p *Policy) replace(ctx context.Context, a *assignment.Assignment, absence string, now time.Time) (Decision, bool) {
+	deployer := a.GithubLogin
+
+	u, _ := p.directory.Get(deployer)
+	if u.Backup != "" {
+		backupAbsence := p.absence(ctx, u.Backup, now)
+		if backupAbsence == "" {
+			a.GithubLogin = u.Backup
+			a.User = p.users[u.Backup]
+			a.Resolution = ResolutionBackup
+			return Decision{
+				Action: ActionBackup,
+				Reason: fmt.Sprintf("%s is %s, putting backup %s on call", deployer, absence, u.Backup),
+			}, true
+		}
+		absence = fmt.Sprintf("%s and backup %s is %s", absence, u.Backup, backupAbsence)
+	}
+
+	t, ok := p.directory.Team(deployer)
+	if !ok || t.Schedule == "" || !p.schedules {
+		return Decision{
+			Action: ActionSkip,
+			Reason: fmt.Sprintf("%s is %s, leaving alerts to regular routing", deployer, absence),
+		}, false
+	}
+
+	a.GithubLogin = ""
+	a.User = ""
+	a.Resolution = ResolutionSchedule
+	a.Responders = []assignment.Responder{{Type: assignment.ResponderSchedule, Name: t.Schedule}}
+	return Decision{
+		Action: ActionSchedule,
+		Reason: fmt.Sprintf("%s is %s, putting schedule %#q of team %s on call", deployer, absence, t.Schedule, t.Name),
+	}, false
+}
+
+// absence asks the sources whether the given GitHub user is absent at the
+// given point in time and returns the reason, empty when they are available.
+// Users are considered available when a source fails.
+func (p *Policy) absence(ctx context.Context, githubLogin string, t time.Time) string {
+	if len(p.sources) == 0 {
+		return ""
+	}
+
+	subject := availability.Subject{
+		GithubLogin: githubLogin,
+		Location:    p.directory.Location(githubLogin),
+		User:        p.users[githubLogin],
+	}
+	if u, ok := p.directory.Get(githubLogin); ok && u.Calendar != "" {
+		subject.Calendars = append(subject.Calendars, u.Calendar)
+	}
+	if team, ok := p.directory.Team(githubLogin); ok {
+		if team.Calendar != "" {
+			subject.Calendars = append(subject.Calendars, team.Calendar)
+		}
+		subject.Schedule = team.Schedule
+	}
+
+	for _, s := range p.sources {
+		absence, err := s.Absence(ctx, subject, t)
+		if err != nil {
+			p.logger.Log("level", "warning", "message", fmt.Sprintf("checking availability of %s in %s failed, considering them available", githubLogin, s.Name()), "stack", fmt.Sprintf("%#v", err))
+			continue
+		}
+		if absence != "" {
+			return absence
+		}
+	}
+
+	return ""
+}
+
+// applyWorkingHours decides on the given assignment based on the working hours
+// of its user. It returns false when the policy does not apply, i.e. with
+// ActionNone or for users without working hours.
+func (p *Policy) applyWorkingHours(a *assignment.Assignment, now time.Time) (Decision, bool) {
+	if p.action == ActionNone {
+		return Decision{}, false
+	}
+	w, ok := p.directory.WorkingHours(a.GithubLogin)
+	if !ok {
+		return Decision{}, false
+	}
+
+	end, working := w.End(now)
+
+	switch {
+	case !working && (p.action == ActionSkip || p.action == ActionShorten):
+		return Decision{
+			Action: ActionSkip,
+			Reason: fmt.Sprintf("deployed outside of working hours %s", w),
+		}, true
+
+	case p.action == ActionShorten && a.Expiry.After(end):
+		a.Expiry = end
+		return Decision{
+			Action: ActionShorten,
+			Reason: fmt.Sprintf("shortened to the end of working hours %s", w),
+		}, true
+
+	case p.action == ActionCoresponder && (!working || a.Expiry.After(end)):
+		a.Responders = append(a.Responders, assignment.Responder{Type: assignment.ResponderSchedule, Name: p.schedule})
+		return Decision{
+			Action: ActionCoresponder,
+			Reason: fmt.Sprintf("on call outside of working hours %s, paging schedule %#q too", w, p.schedule),
+		}, true
+	}
+
+	return Decision{
+		Action: ActionNone,
+		Reason: fmt.Sprintf("deployed within working hours %s", w),
+	}, true
+}
@@ -0,0 +1,238 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/availability"
+	"github.com/giantswarm/auto-oncall/service/directory"
+)
+
+// source is an availability source with fixed absences by GitHub login.
+// Lookups of users listed in failing fail.
+type source struct {
+	absences map[string]string
+	failing  map[string]bool
+}
+
+func (s source) Name() string {
+	return "test"
+}
+
+func (s source) Absence(ctx context.Context, subject availability.Subject, t time.Time) (string, error) {
+	if s.failing[subject.GithubLogin] {
+		return "", errors.New("lookup failed")
+	}
+
+	return s.absences[subject.GithubLogin], nil
+}
+
+func Test_Policy_Apply(t *testing.T) {
+	// Monday, within the working hours of johndoe and bob.
+	now := time.Date(2020, 5, 4, 10, 0, 0, 0, time.UTC)
+
+	d, err := directory.New(
+		[]directory.User{
+			{Github: "johndoe", Team: "ops", Backup: "jane", WorkingHours: "09:00-17:00"},
+			{Github: "jane", Team: "ops"},
+			{Github: "alice", Team: "dev"},
+			{Github: "bob", WorkingHours: "09:00-17:00"},
+		},
+		[]directory.Team{
+			{Name: "ops", Schedule: "ops_schedule"},
+			{Name: "dev"},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := map[string]string{
+		"johndoe": "john@example.com",
+		"jane":    "jane@example.com",
+		"alice":   "alice@example.com",
+		"bob":     "bob@example.com",
+	}
+
+	testCases := []struct {
+		name                string
+		overrides           bool
+		outsideWorkingHours string
+		absences            map[string]string
+		failing             map[string]bool
+		deployer            string
+		deployed            time.Time
+		expiresIn           time.Duration
+		expectedActions     []string
+		expectedAssignment  assignment.Assignment
+	}{
+		{
+			name:               "case 0: available deployer without working hours policy",
+			deployer:           "johndoe",
+			deployed:           now,
+			expiresIn:          time.Hour,
+			expectedAssignment: assignment.Assignment{GithubLogin: "johndoe", User: "john@example.com", Expiry: now.Add(time.Hour)},
+		},
+		{
+			name:               "case 1: absent deployer handed to available backup",
+			absences:           map[string]string{"johndoe": "on vacation"},
+			deployer:           "johndoe",
+			deployed:           now,
+			expiresIn:          time.Hour,
+			expectedActions:    []string{ActionBackup},
+			expectedAssignment: assignment.Assignment{GithubLogin: "jane", User: "jane@example.com", Resolution: ResolutionBackup, Expiry: now.Add(time.Hour)},
+		},
+		{
+			name:            "case 2: absent deployer and backup handed to the team schedule",
+			absences:        map[string]string{"johndoe": "on vacation", "jane": "sick"},
+			deployer:        "johndoe",
+			deployed:        now,
+			expiresIn:       time.Hour,
+			expectedActions: []string{ActionSchedule},
+			expectedAssignment: assignment.Assignment{
+				Resolution: ResolutionSchedule,
+				Expiry:     now.Add(time.Hour),
+				Responders: []assignment.Responder{{Type: assignment.ResponderSchedule, Name: "ops_schedule"}},
+			},
+		},
+		{
+			name:               "case 3: absent deployer and backup skipped without schedules in the provider",
+			overrides:          true,
+			absences:           map[string]string{"johndoe": "on vacation", "jane": "sick"},
+			deployer:           "johndoe",
+			deployed:           now,
+			expiresIn:          time.Hour,
+			expectedActions:    []string{ActionSkip},
+			expectedAssignment: assignment.Assignment{GithubLogin: "johndoe", User: "john@example.com", Expiry: now.Add(time.Hour)},
+		},
+		{
+			name:               "case 4: absent deployer without backup and schedule skipped",
+			absences:           map[string]string{"alice": "on vacation"},
+			deployer:           "alice",
+			deployed:           now,
+			expiresIn:          time.Hour,
+			expectedActions:    []string{ActionSkip},
+			expectedAssignment: assignment.Assignment{GithubLogin: "alice", User: "alice@example.com", Expiry: now.Add(time.Hour)},
+		},
+		{
+			name:                "case 5: working hours not applied to absent deployer",
+			outsideWorkingHours: ActionShorten,
+			absences:            map[string]string{"johndoe": "on vacation", "jane": "sick"},
+			deployer:            "johndoe",
+			deployed:            now,
+			expiresIn:           10 * time.Hour,
+			expectedActions:     []string{ActionSchedule},
+			expectedAssignment: assignment.Assignment{
+				Resolution: ResolutionSchedule,
+				Expiry:     now.Add(10 * time.Hour),
+				Responders: []assignment.Responder{{Type: assignment.ResponderSchedule, Name: "ops_schedule"}},
+			},
+		},
+		{
+			name:                "case 6: deployer considered available when the source fails",
+			outsideWorkingHours: ActionShorten,
+			failing:             map[string]bool{"bob": true},
+			deployer:            "bob",
+			deployed:            now,
+			expiresIn:           10 * time.Hour,
+			expectedActions:     []string{ActionShorten},
+			expectedAssignment:  assignment.Assignment{GithubLogin: "bob", User: "bob@example.com", Expiry: time.Date(2020, 5, 4, 17, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:                "case 7: deployed within working hours",
+			outsideWorkingHours: ActionShorten,
+			deployer:            "bob",
+			deployed:            now,
+			expiresIn:           time.Hour,
+			expectedActions:     []string{ActionNone},
+			expectedAssignment:  assignment.Assignment{GithubLogin: "bob", User: "bob@example.com", Expiry: now.Add(time.Hour)},
+		},
+		{
+			name:                "case 8: deployed outside of working hours",
+			outsideWorkingHours: ActionSkip,
+			deployer:            "bob",
+			deployed:            now.Add(8 * time.Hour),
+			expiresIn:           time.Hour,
+			expectedActions:     []string{ActionSkip},
+			expectedAssignment:  assignment.Assignment{GithubLogin: "bob", User: "bob@example.com", Expiry: now.Add(9 * time.Hour)},
+		},
+		{
+			name:                "case 9: regular schedule paged outside of working hours",
+			outsideWorkingHours: ActionCoresponder,
+			deployer:            "bob",
+			deployed:            now,
+			expiresIn:           10 * time.Hour,
+			expectedActions:     []string{ActionCoresponder},
+			expectedAssignment: assignment.Assignment{
+				GithubLogin: "bob",
+				User:        "bob@example.com",
+				Expiry:      now.Add(10 * time.Hour),
+				Responders:  []assignment.Responder{{Type: assignment.ResponderSchedule, Name: "regular"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := Config{
+				Directory: d,
+				Logger:    logger,
+
+				OutsideWorkingHours: tc.outsideWorkingHours,
+				Schedule:            "regular",
+				Schedules:           !tc.overrides,
+				Sources:             []availability.Source{source{absences: tc.absences, failing: tc.failing}},
+				Users:               users,
+			}
+
+			p, err := New(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a := assignment.Assignment{
+				GithubLogin: tc.deployer,
+				User:        users[tc.deployer],
+				Expiry:      tc.deployed.Add(tc.expiresIn),
+			}
+
+			var actions []string
+			for _, d := range p.Apply(context.Background(), &a, tc.deployed) {
+				if d.Reason == "" {
+					t.Fatalf("expected reason for action %#q", d.Action)
+				}
+				actions = append(actions, d.Action)
+			}
+
+			if !reflect.DeepEqual(actions, tc.expectedActions) {
+				t.Fatalf("expected actions %v, got %v", tc.expectedActions, actions)
+			}
+			if !reflect.DeepEqual(a, tc.expectedAssignment) {
+				t.Fatalf("expected assignment %#v, got %#v", tc.expectedAssignment, a)
+			}
+
+			// Assignments of absent deployers that are made must not page
+			// them in any way.
+			if tc.absences[tc.deployer] != "" && actions[0] != ActionSkip {
+				targets := append([]assignment.Responder{{Type: assignment.ResponderUser, Name: a.User}}, a.Responders...)
+				for _, r := range targets {
+					if r.Type == assignment.ResponderUser && r.Name == users[tc.deployer] || a.GithubLogin == tc.deployer {
+						t.Fatalf("expected absent deployer %#q not to be on call, got %#v", tc.deployer, a)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package alertmanager
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+var executionFailedError = &microerror.Error{
+	Kind: "executionFailedError",
+}
+
+// IsExecutionFailed asserts executionFailedError.
+func IsExecutionFailed(err error) bool {
+	return microerror.Cause(err) == executionFailedError
+}
+
+var invalidConfigError = &microerror.Error{
+	Kind: "invalidConfigError",
+}
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var receiverNotFoundError = &microerror.Error{
+	Kind: "receiverNotFoundError",
+}
+
+// IsReceiverNotFound asserts receiverNotFoundError.
+func IsReceiverNotFound(err error) bool {
+	return microerror.Cause(err) == receiverNotFoundError
+}
+
+var routeNotFoundError = &microerror.Error{
+	Kind: "routeNotFoundError",
+}
+
+// IsRouteNotFound asserts routeNotFoundError.
+func IsRouteNotFound(err error) bool {
+	return microerror.Cause(err) == routeNotFoundError
+}
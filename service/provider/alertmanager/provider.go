@@ -0,0 +1,553 @@
+// Package alertmanager implements the Prometheus Alertmanager provider. It
+// renders the configured base Alertmanager configuration together with one
+// time-bounded route per assignment into the configuration file Alertmanager
+// is started with, and asks Alertmanager to reload it.
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/provider"
+)
+
+const (
+	installationLabel = "installation"
+	repositoryLabel   = "repository"
+
+	managedPrefix = "auto-"
+
+	reloadOperation = "reload"
+)
+
+type Config struct {
+	HttpClient *http.Client
+	Logger     micrologger.Logger
+
+	// BaseConfig is the path of the Alertmanager configuration managed routes
+	// are added to.
+	BaseConfig string
+	// OutputConfig is the path the resulting Alertmanager configuration is
+	// written to.
+	OutputConfig string
+	// ReloadURL is the optional Alertmanager reload endpoint, e.g.
+	// http://alertmanager:9093/-/reload.
+	ReloadURL string
+}
+
+type Provider struct {
+	httpClient *http.Client
+	logger     micrologger.Logger
+
+	baseConfig   string
+	outputConfig string
+	reloadURL    string
+
+	routes []route
+	mutex  sync.Mutex
+}
+
+// route is the managed routing of a single assignment. Alerts are sent to
+// all receivers, the first one being the receiver of the deployer, or of the
+// schedule on call instead.
+type route struct {
+	name      string
+	receivers []string
+	matchers  []string
+	start     time.Time
+	expiry    time.Time
+}
+
+func New(config Config) (*Provider, error) {
+	if config.HttpClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.HttpClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.BaseConfig == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.BaseConfig must not be empty", config)
+	}
+	if config.OutputConfig == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.OutputConfig must not be empty", config)
+	}
+
+	p := &Provider{
+		httpClient: config.HttpClient,
+		logger:     config.Logger,
+
+		baseConfig:   config.BaseConfig,
+		outputConfig: config.OutputConfig,
+		reloadURL:    config.ReloadURL,
+	}
+
+	// Routes written before a restart are recovered from the output
+	// configuration, so they survive until they expire.
+	routes, err := p.readRoutes()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	p.routes = routes
+
+	return p, nil
+}
+
+// Check verifies that the base configuration can be read.
+func (p *Provider) Check() error {
+	_, err := ioutil.ReadFile(p.baseConfig)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Create adds a route sending alerts labelled with the repository and the
+// environment of the assignment to the receiver of the assigned user, and to
+// the receivers of its responders. The route is only active until the
+// assignment expires.
+func (p *Provider) Create(ctx context.Context, a *assignment.Assignment) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	r := newRoute(*a, time.Now().UTC())
+	err := p.update(ctx, r, a.Name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	p.logger.Log("level", "debug", "message", fmt.Sprintf("route %#q for receiver %#q has been created", r.name, a.User))
+
+	return nil
+}
+
+// Extend moves the end of the time interval of the route of the given
+// assignment to its new expiry.
+func (p *Provider) Extend(ctx context.Context, a assignment.Assignment) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, r := range p.routes {
+		if r.name != a.Name {
+			continue
+		}
+
+		r.expiry = a.Expiry
+		err := p.update(ctx, r, a.Name)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		p.logger.Log("level", "debug", "message", fmt.Sprintf("route %#q has been extended until %s", a.Name, a.Expiry.Format(time.RFC3339)))
+
+		return nil
+	}
+
+	return microerror.Maskf(routeNotFoundError, "%#q", a.Name)
+}
+
+// Delete removes the route of the given assignment.
+func (p *Provider) Delete(ctx context.Context, a assignment.Assignment) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	err := p.update(ctx, route{}, a.Name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	p.logger.Log("level", "debug", "message", fmt.Sprintf("route %#q has been deleted", a.Name))
+
+	return nil
+}
+
+// Reconcile renders the routes of exactly the given assignments. Routes
+// already rendered keep their start.
+func (p *Provider) Reconcile(ctx context.Context, active []assignment.Assignment) ([]assignment.Assignment, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	starts := map[string]time.Time{}
+	for _, r := range p.routes {
+		starts[r.name] = r.start
+	}
+
+	now := time.Now().UTC()
+
+	var routes []route
+	for _, a := range active {
+		start, ok := starts[a.Name]
+		if !ok {
+			p.logger.Log("level", "info", "message", fmt.Sprintf("recreating missing route %#q", a.Name))
+			start = now
+		}
+		routes = append(routes, newRoute(a, start))
+	}
+	for _, r := range p.routes {
+		if !contains(active, r.name) {
+			p.logger.Log("level", "info", "message", fmt.Sprintf("deleting orphaned route %#q", r.name))
+		}
+	}
+
+	err := p.write(routes)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	p.routes = routes
+
+	err = p.reload(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return active, nil
+}
+
+// update replaces the route with the given name by the given route, if it has
+// a name, drops expired routes and writes and reloads the configuration.
+func (p *Provider) update(ctx context.Context, r route, name string) error {
+	now := time.Now().UTC()
+
+	var routes []route
+	if r.name != "" {
+		routes = append(routes, r)
+	}
+	for _, existing := range p.routes {
+		if existing.name != name && existing.expiry.After(now) {
+			routes = append(routes, existing)
+		}
+	}
+
+	err := p.write(routes)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	p.routes = routes
+
+	err = p.reload(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// write renders the base configuration together with the given routes to the
+// output configuration.
+func (p *Provider) write(routes []route) error {
+	b, err := ioutil.ReadFile(p.baseConfig)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var config yaml.MapSlice
+	err = yaml.Unmarshal(b, &config)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	receivers := map[string]bool{}
+	for _, r := range sliceValue(config, "receivers") {
+		if m, ok := r.(yaml.MapSlice); ok {
+			receivers[fmt.Sprint(mapValue(m, "name"))] = true
+		}
+	}
+
+	var managedRoutes []interface{}
+	var managedIntervals []interface{}
+	for _, r := range routes {
+		// Responders are routed first and let the alert continue, so the
+		// route of the deployer ends the routing of matching alerts.
+		for i := len(r.receivers) - 1; i >= 0; i-- {
+			if !receivers[r.receivers[i]] {
+				return microerror.Maskf(receiverNotFoundError, "%#q", r.receivers[i])
+			}
+
+			managedRoutes = append(managedRoutes, yaml.MapSlice{
+				{Key: "receiver", Value: r.receivers[i]},
+				{Key: "matchers", Value: r.matchers},
+				{Key: "active_time_intervals", Value: []string{r.name}},
+				{Key: "continue", Value: i != 0},
+			})
+		}
+		managedIntervals = append(managedIntervals, yaml.MapSlice{
+			{Key: "name", Value: r.name},
+			{Key: "time_intervals", Value: timeIntervals(r.start, r.expiry)},
+		})
+	}
+
+	// Managed routes are prepended to the child routes of the root route, so
+	// they take precedence over the routing of the base configuration.
+	rootRoute, _ := mapValue(config, "route").(yaml.MapSlice)
+	if rootRoute == nil {
+		return microerror.Maskf(invalidConfigError, "%#q has no root route", p.baseConfig)
+	}
+	rootRoute = setValue(rootRoute, "routes", append(managedRoutes, sliceValue(rootRoute, "routes")...))
+	config = setValue(config, "route", rootRoute)
+	config = setValue(config, "time_intervals", append(sliceValue(config, "time_intervals"), managedIntervals...))
+
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// The configuration is written to a temporary file first and renamed
+	// afterwards, so Alertmanager never reads a partially written file.
+	tmp := filepath.Join(filepath.Dir(p.outputConfig), "."+filepath.Base(p.outputConfig)+".tmp")
+	err = ioutil.WriteFile(tmp, out, 0644)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = os.Rename(tmp, p.outputConfig)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// readRoutes returns the unexpired managed routes of the output
+// configuration. The expiry of a route is the end of its time interval, as
+// assignments may have been extended after their name was chosen.
+func (p *Provider) readRoutes() ([]route, error) {
+	b, err := ioutil.ReadFile(p.outputConfig)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var config yaml.MapSlice
+	err = yaml.Unmarshal(b, &config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	now := time.Now().UTC()
+
+	expiries := map[string]time.Time{}
+	for _, v := range sliceValue(config, "time_intervals") {
+		m, ok := v.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+
+		name := fmt.Sprint(mapValue(m, "name"))
+		if strings.HasPrefix(name, managedPrefix) {
+			expiries[name] = intervalsEnd(sliceValue(m, "time_intervals"))
+		}
+	}
+
+	var routes []route
+	byName := map[string]int{}
+	rootRoute, _ := mapValue(config, "route").(yaml.MapSlice)
+	for _, v := range sliceValue(rootRoute, "routes") {
+		m, ok := v.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+
+		for _, i := range sliceValue(m, "active_time_intervals") {
+			name := fmt.Sprint(i)
+			expiry, ok := expiries[name]
+			if !ok || !expiry.After(now) {
+				continue
+			}
+
+			// Routes of responders come first, so receivers are prepended to
+			// keep the receiver of the deployer first.
+			receiver := fmt.Sprint(mapValue(m, "receiver"))
+			if i, ok := byName[name]; ok {
+				routes[i].receivers = append([]string{receiver}, routes[i].receivers...)
+				continue
+			}
+
+			r := route{
+				name:      name,
+				receivers: []string{receiver},
+				start:     now,
+				expiry:    expiry,
+			}
+			for _, matcher := range sliceValue(m, "matchers") {
+				r.matchers = append(r.matchers, fmt.Sprint(matcher))
+			}
+			byName[name] = len(routes)
+			routes = append(routes, r)
+		}
+	}
+
+	return routes, nil
+}
+
+func (p *Provider) reload(ctx context.Context) error {
+	if p.reloadURL == "" {
+		return nil
+	}
+
+	req, err := http.NewRequest("POST", p.reloadURL, nil)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		provider.ObserveRequest(provider.Alertmanager, reloadOperation, 0)
+		return microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	provider.ObserveRequest(provider.Alertmanager, reloadOperation, resp.StatusCode)
+
+	if resp.StatusCode != http.StatusOK {
+		return microerror.Maskf(executionFailedError, "reloading alertmanager: expected 200, got %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// newRoute returns the route of the given assignment, active from start
+// until the assignment expires.
+func newRoute(a assignment.Assignment, start time.Time) route {
+	r := route{
+		name: a.Name,
+		matchers: []string{
+			fmt.Sprintf("%s=%q", repositoryLabel, a.Repository),
+			fmt.Sprintf("%s=%q", installationLabel, a.Environment),
+		},
+		start:  start,
+		expiry: a.Expiry,
+	}
+	if a.User != "" {
+		r.receivers = append(r.receivers, a.User)
+	}
+	// Schedules are routed to the receiver of the same name, like users.
+	for _, responder := range a.Responders {
+		if responder.Type == assignment.ResponderUser || responder.Type == assignment.ResponderSchedule {
+			r.receivers = append(r.receivers, responder.Name)
+		}
+	}
+
+	return r
+}
+
+func contains(assignments []assignment.Assignment, name string) bool {
+	for _, a := range assignments {
+		if a.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// timeIntervals returns Alertmanager time intervals covering the UTC time
+// range from start to end, with one interval per day.
+func timeIntervals(start, end time.Time) []interface{} {
+	start = start.UTC().Truncate(time.Minute)
+	end = end.UTC().Truncate(time.Minute)
+
+	var intervals []interface{}
+	for day := start.Truncate(24 * time.Hour); day.Before(end); day = day.Add(24 * time.Hour) {
+		startTime := "00:00"
+		if day.Before(start) {
+			startTime = start.Format("15:04")
+		}
+		endTime := "24:00"
+		if day.Add(24 * time.Hour).After(end) {
+			endTime = end.Format("15:04")
+		}
+		if startTime == endTime {
+			continue
+		}
+
+		intervals = append(intervals, yaml.MapSlice{
+			{Key: "times", Value: []yaml.MapSlice{{{Key: "start_time", Value: startTime}, {Key: "end_time", Value: endTime}}}},
+			{Key: "days_of_month", Value: []string{strconv.Itoa(day.Day())}},
+			{Key: "months", Value: []string{strings.ToLower(day.Month().String())}},
+			{Key: "years", Value: []string{strconv.Itoa(day.Year())}},
+		})
+	}
+
+	return intervals
+}
+
+// intervalsEnd returns the end of the latest of the given time intervals, as
+// rendered by timeIntervals.
+func intervalsEnd(intervals []interface{}) time.Time {
+	var end time.Time
+	for _, v := range intervals {
+		m, ok := v.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+
+		var date []string
+		for _, key := range []string{"years", "months", "days_of_month"} {
+			values := sliceValue(m, key)
+			if len(values) != 1 {
+				break
+			}
+			date = append(date, fmt.Sprint(values[0]))
+		}
+		times := sliceValue(m, "times")
+		if len(date) != 3 || len(times) != 1 {
+			continue
+		}
+		t, _ := times[0].(yaml.MapSlice)
+
+		day, err := time.Parse("2006 January 2", strings.Join(date, " "))
+		if err != nil {
+			continue
+		}
+		var hour, minute int
+		_, err = fmt.Sscanf(fmt.Sprint(mapValue(t, "end_time")), "%d:%d", &hour, &minute)
+		if err != nil {
+			continue
+		}
+		e := day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+
+		if e.After(end) {
+			end = e
+		}
+	}
+
+	return end
+}
+
+func mapValue(m yaml.MapSlice, key string) interface{} {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+
+	return nil
+}
+
+func setValue(m yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i, item := range m {
+		if item.Key == key {
+			m[i].Value = value
+			return m
+		}
+	}
+
+	return append(m, yaml.MapItem{Key: key, Value: value})
+}
+
+func sliceValue(m yaml.MapSlice, key string) []interface{} {
+	s, _ := mapValue(m, key).([]interface{})
+	return s
+}
@@ -0,0 +1,276 @@
+package alertmanager
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/micrologger"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+)
+
+const (
+	testBaseConfig = `route:
+  receiver: team
+  routes:
+  - receiver: team
+    matchers:
+    - severity="page"
+receivers:
+- name: team
+- name: john
+- name: ops
+time_intervals:
+- name: office
+  time_intervals:
+  - weekdays: ["monday:friday"]
+`
+)
+
+func Test_timeIntervals(t *testing.T) {
+	testCases := []struct {
+		name        string
+		start       time.Time
+		end         time.Time
+		expected    string
+		expectedEnd time.Time
+	}{
+		{
+			name:  "case 0: within a day",
+			start: time.Date(2024, time.March, 5, 9, 30, 45, 0, time.UTC),
+			end:   time.Date(2024, time.March, 5, 17, 0, 0, 0, time.UTC),
+			expected: `- times:
+  - start_time: "09:30"
+    end_time: "17:00"
+  days_of_month: ["5"]
+  months: [march]
+  years: ["2024"]
+`,
+			expectedEnd: time.Date(2024, time.March, 5, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "case 1: over several days",
+			start: time.Date(2024, time.February, 28, 22, 15, 0, 0, time.UTC),
+			end:   time.Date(2024, time.March, 1, 3, 30, 0, 0, time.UTC),
+			expected: `- times:
+  - start_time: "22:15"
+    end_time: "24:00"
+  days_of_month: ["28"]
+  months: [february]
+  years: ["2024"]
+- times:
+  - start_time: "00:00"
+    end_time: "24:00"
+  days_of_month: ["29"]
+  months: [february]
+  years: ["2024"]
+- times:
+  - start_time: "00:00"
+    end_time: "03:30"
+  days_of_month: ["1"]
+  months: [march]
+  years: ["2024"]
+`,
+			expectedEnd: time.Date(2024, time.March, 1, 3, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "case 2: ending at midnight",
+			start: time.Date(2024, time.December, 31, 22, 0, 0, 0, time.UTC),
+			end:   time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: `- times:
+  - start_time: "22:00"
+    end_time: "24:00"
+  days_of_month: ["31"]
+  months: [december]
+  years: ["2024"]
+`,
+			expectedEnd: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "case 3: in another time zone",
+			start: time.Date(2024, time.March, 5, 10, 0, 0, 0, time.FixedZone("CET", 3600)),
+			end:   time.Date(2024, time.March, 5, 11, 0, 0, 0, time.FixedZone("CET", 3600)),
+			expected: `- times:
+  - start_time: "09:00"
+    end_time: "10:00"
+  days_of_month: ["5"]
+  months: [march]
+  years: ["2024"]
+`,
+			expectedEnd: time.Date(2024, time.March, 5, 10, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The intervals are read back like the output configuration after
+			// a restart.
+			b, err := yaml.Marshal(yaml.MapSlice{{Key: "time_intervals", Value: timeIntervals(tc.start, tc.end)}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var config, expected yaml.MapSlice
+			err = yaml.Unmarshal(b, &config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = yaml.Unmarshal([]byte("time_intervals:\n"+tc.expected), &expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rendered := sliceValue(config, "time_intervals")
+			if !reflect.DeepEqual(rendered, sliceValue(expected, "time_intervals")) {
+				t.Fatalf("expected %#q, got %#q", tc.expected, b)
+			}
+
+			end := intervalsEnd(rendered)
+			if !end.Equal(tc.expectedEnd) {
+				t.Fatalf("expected end %s, got %s", tc.expectedEnd, end)
+			}
+		})
+	}
+}
+
+func Test_Provider_Restart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auto-oncall-alertmanager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Config{
+		HttpClient: http.DefaultClient,
+		Logger:     logger,
+
+		BaseConfig:   filepath.Join(dir, "base.yaml"),
+		OutputConfig: filepath.Join(dir, "alertmanager.yaml"),
+	}
+	err = ioutil.WriteFile(c.BaseConfig, []byte(testBaseConfig), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiry := time.Now().UTC().Add(26 * time.Hour).Truncate(time.Minute)
+	a := assignment.New("aws-operator", "v1.0.0", "anteater", "johndoe", "john", expiry)
+	a.Responders = []assignment.Responder{{Type: assignment.ResponderUser, Name: "ops"}}
+
+	err = p.Create(context.Background(), &a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(c.OutputConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config yaml.MapSlice
+	err = yaml.Unmarshal(b, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Responders are routed first and continue to the route of the deployer,
+	// followed by the routes of the base configuration.
+	var receivers []string
+	var continues []bool
+	rootRoute, _ := mapValue(config, "route").(yaml.MapSlice)
+	for _, v := range sliceValue(rootRoute, "routes") {
+		m, _ := v.(yaml.MapSlice)
+		receivers = append(receivers, mapValue(m, "receiver").(string))
+		cont, _ := mapValue(m, "continue").(bool)
+		continues = append(continues, cont)
+	}
+	if !reflect.DeepEqual(receivers, []string{"ops", "john", "team"}) || !reflect.DeepEqual(continues, []bool{true, false, false}) {
+		t.Fatalf("expected routes to ops and john before the base routes, got %#v with continue %#v", receivers, continues)
+	}
+	intervals := sliceValue(config, "time_intervals")
+	if len(intervals) != 2 || mapValue(intervals[0].(yaml.MapSlice), "name") != "office" || mapValue(intervals[1].(yaml.MapSlice), "name") != a.Name {
+		t.Fatalf("expected time intervals of the base configuration and %#q, got %#v", a.Name, intervals)
+	}
+
+	// Routes are recovered from the output configuration after a restart.
+	restarted, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restarted.routes) != 1 {
+		t.Fatalf("expected 1 recovered route, got %#v", restarted.routes)
+	}
+	r := restarted.routes[0]
+	if r.name != a.Name || !reflect.DeepEqual(r.receivers, []string{"john", "ops"}) || !reflect.DeepEqual(r.matchers, newRoute(a, time.Now()).matchers) || !r.expiry.Equal(expiry) {
+		t.Fatalf("expected route %#q to john and ops until %s, got %#v", a.Name, expiry, r)
+	}
+
+	// The recovered route can be extended and deleted.
+	a.Expiry = expiry.Add(time.Hour)
+	err = restarted.Extend(context.Background(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = restarted.Delete(context.Background(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restarted, err = New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restarted.routes) != 0 {
+		t.Fatalf("expected no route after deletion, got %#v", restarted.routes)
+	}
+}
+
+func Test_Provider_Create_ReceiverNotFound(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auto-oncall-alertmanager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Config{
+		HttpClient: http.DefaultClient,
+		Logger:     logger,
+
+		BaseConfig:   filepath.Join(dir, "base.yaml"),
+		OutputConfig: filepath.Join(dir, "alertmanager.yaml"),
+	}
+	err = ioutil.WriteFile(c.BaseConfig, []byte(testBaseConfig), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := assignment.New("aws-operator", "v1.0.0", "anteater", "janedoe", "jane", time.Now().Add(time.Hour))
+	err = p.Create(context.Background(), &a)
+	if !IsReceiverNotFound(err) {
+		t.Fatalf("expected receiver not found error, got %#v", err)
+	}
+}
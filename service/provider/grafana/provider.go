@@ -0,0 +1,699 @@
+// Package grafana implements the Grafana OnCall provider. Depending on its
+// mode an assignment becomes either a temporary override shift on a schedule
+// or a route on an integration escalating to the deployer.
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/provider"
+)
+
+const (
+	// ModeOverride creates an override shift for the deployer on the
+	// configured schedule.
+	ModeOverride = "override"
+	// ModeRoute creates a route on the configured integration escalating to
+	// the deployer.
+	ModeRoute = "route"
+)
+
+const (
+	escalationChainEndpoint    = "/api/v1/escalation_chains/%s/"
+	escalationChainsEndpoint   = "/api/v1/escalation_chains/"
+	escalationPoliciesEndpoint = "/api/v1/escalation_policies/"
+	onCallShiftEndpoint        = "/api/v1/on_call_shifts/%s/"
+	onCallShiftsEndpoint       = "/api/v1/on_call_shifts/"
+	routeEndpoint              = "/api/v1/routes/%s/"
+	routesEndpoint             = "/api/v1/routes/"
+	schedulesEndpoint          = "/api/v1/schedules/%s/"
+	usersEndpoint              = "/api/v1/users/"
+
+	escalationChainID = "escalationchain"
+	onCallShiftID     = "oncallshift"
+	routeID           = "route"
+
+	escalationPolicyType = "notify_persons"
+	schedulePolicyType   = "notify_on_call_from_schedule"
+	managedPrefix        = "auto-"
+	routingType          = "regex"
+	shiftTimeFormat      = "2006-01-02T15:04:05"
+	shiftTimeZone        = "UTC"
+	shiftType            = "override"
+)
+
+// resources are the Grafana OnCall API resources requests are counted by.
+var resources = []string{"escalation_chains", "escalation_policies", "on_call_shifts", "routes", "schedules", "users"}
+
+type Config struct {
+	HttpClient *http.Client
+	Logger     micrologger.Logger
+
+	// Integration is the ID of the integration routes are created on in route
+	// mode.
+	Integration string
+	// Mode is either ModeOverride or ModeRoute.
+	Mode string
+	// Schedule is the ID of the schedule overrides are created on in override
+	// mode.
+	Schedule string
+	// Team is the optional ID of the team owning created objects.
+	Team  string
+	Token string
+	// URL is the base URL of the Grafana OnCall API, e.g.
+	// https://oncall-prod-eu-west-0.grafana.net/oncall.
+	URL string
+}
+
+type Provider struct {
+	httpClient *http.Client
+	logger     micrologger.Logger
+
+	integration string
+	mode        string
+	schedule    string
+	team        string
+	token       string
+	url         string
+
+	// scheduleLock serializes updates of the shifts of the schedule, which
+	// are read, changed and written back.
+	scheduleLock sync.Mutex
+	userIDs      map[string]string
+	userLock     sync.Mutex
+}
+
+func New(config Config) (*Provider, error) {
+	if config.HttpClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.HttpClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.Token == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Token must not be empty", config)
+	}
+	if config.URL == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.URL must not be empty", config)
+	}
+	switch config.Mode {
+	case ModeOverride:
+		if config.Schedule == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Schedule must not be empty in %#q mode", config, config.Mode)
+		}
+	case ModeRoute:
+		if config.Integration == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Integration must not be empty in %#q mode", config, config.Mode)
+		}
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "%T.Mode must be %#q or %#q, got %#q", config, ModeOverride, ModeRoute, config.Mode)
+	}
+
+	p := &Provider{
+		httpClient: config.HttpClient,
+		logger:     config.Logger,
+
+		integration: config.Integration,
+		mode:        config.Mode,
+		schedule:    config.Schedule,
+		team:        config.Team,
+		token:       config.Token,
+		url:         strings.TrimSuffix(config.URL, "/"),
+
+		userIDs: map[string]string{},
+	}
+
+	return p, nil
+}
+
+// Check verifies that the API token is valid by listing users.
+func (p *Provider) Check() error {
+	ctx := context.Background()
+
+	var list UserList
+	err := p.do(ctx, "GET", p.url+usersEndpoint, nil, &list)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Create resolves the assigned user and its responders to their Grafana
+// OnCall user IDs and creates either an override shift or a route for them,
+// depending on the mode. The IDs of created objects are recorded in the
+// assignment.
+func (p *Provider) Create(ctx context.Context, a *assignment.Assignment) error {
+	var userIDs []string
+	{
+		var users []string
+		if a.User != "" {
+			users = append(users, a.User)
+		}
+		for _, r := range a.Responders {
+			if r.Type == assignment.ResponderUser {
+				users = append(users, r.Name)
+			}
+		}
+
+		for _, u := range users {
+			id, err := p.userID(ctx, u)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			userIDs = append(userIDs, id)
+		}
+	}
+
+	var err error
+	if p.mode == ModeOverride {
+		err = p.createOverride(ctx, a, userIDs)
+	} else {
+		err = p.createRoute(ctx, a, userIDs)
+	}
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Extend extends the override shift of the given assignment. Routes are not
+// bound in time, they stay until they are deleted.
+func (p *Provider) Extend(ctx context.Context, a assignment.Assignment) error {
+	if p.mode == ModeOverride {
+		err := p.extendOverride(ctx, a)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the override shift, or the route and escalation chain, of
+// the given assignment.
+func (p *Provider) Delete(ctx context.Context, a assignment.Assignment) error {
+	var err error
+	if p.mode == ModeOverride {
+		err = p.deleteOverride(ctx, a)
+	} else {
+		err = p.deleteRoute(ctx, a)
+	}
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Reconcile recreates the override shifts, or the routes and escalation
+// chains, of the given assignments and deletes managed ones not belonging to
+// any of them.
+func (p *Provider) Reconcile(ctx context.Context, active []assignment.Assignment) ([]assignment.Assignment, error) {
+	names := map[string]bool{}
+	for _, a := range active {
+		names[a.Name] = true
+	}
+
+	existing := map[string]bool{}
+	if p.mode == ModeOverride {
+		shifts, err := p.scheduleShifts(ctx)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, shift := range shifts {
+			existing[shift.Name] = true
+			if names[shift.Name] {
+				continue
+			}
+
+			p.logger.Log("level", "info", "message", fmt.Sprintf("deleting orphaned override %#q", shift.Name))
+
+			err = p.do(ctx, "DELETE", p.url+fmt.Sprintf(onCallShiftEndpoint, shift.ID), nil, nil)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+	} else {
+		chains, err := p.integrationChains(ctx)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, chain := range chains {
+			existing[chain.Name] = true
+			if names[chain.Name] {
+				continue
+			}
+
+			p.logger.Log("level", "info", "message", fmt.Sprintf("deleting orphaned route %#q", chain.Name))
+
+			err = p.deleteRoute(ctx, assignment.Assignment{Name: chain.Name})
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+	}
+
+	for i := range active {
+		if existing[active[i].Name] {
+			continue
+		}
+
+		p.logger.Log("level", "info", "message", fmt.Sprintf("recreating missing assignment %#q", active[i].Name))
+
+		err := p.Create(ctx, &active[i])
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return active, nil
+}
+
+func (p *Provider) createOverride(ctx context.Context, a *assignment.Assignment, userIDs []string) error {
+	now := time.Now().UTC()
+
+	shift := OnCallShift{
+		Duration: int64(a.Expiry.Sub(now) / time.Second),
+		Name:     a.Name,
+		Start:    now.Format(shiftTimeFormat),
+		TeamID:   p.team,
+		TimeZone: shiftTimeZone,
+		Type:     shiftType,
+		Users:    userIDs,
+	}
+	err := p.do(ctx, "POST", p.url+onCallShiftsEndpoint, shift, &shift)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	a.SetID(onCallShiftID, shift.ID)
+
+	// Overrides only take effect once they are part of the schedule, so we
+	// append the new shift to the shifts of the schedule. Only the shifts are
+	// sent, the shifts are read right before to keep the window for lost
+	// updates by others small.
+	var schedule Schedule
+	{
+		p.scheduleLock.Lock()
+		defer p.scheduleLock.Unlock()
+
+		endpoint := p.url + fmt.Sprintf(schedulesEndpoint, p.schedule)
+
+		err = p.do(ctx, "GET", endpoint, nil, &schedule)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		update := Schedule{
+			Shifts: append(schedule.Shifts, shift.ID),
+		}
+		err = p.do(ctx, "PUT", endpoint, update, nil)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+	p.logger.Log("level", "debug", "message", fmt.Sprintf("override %#q for user %#q has been created on schedule %#q", a.Name, a.User, schedule.Name))
+
+	return nil
+}
+
+func (p *Provider) createRoute(ctx context.Context, a *assignment.Assignment, userIDs []string) error {
+	chain := EscalationChain{
+		Name:   a.Name,
+		TeamID: p.team,
+	}
+	err := p.do(ctx, "POST", p.url+escalationChainsEndpoint, chain, &chain)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	a.SetID(escalationChainID, chain.ID)
+
+	// Assignments of a schedule instead of a user have no users to notify.
+	var position int
+	if len(userIDs) > 0 {
+		policy := EscalationPolicy{
+			EscalationChainID: chain.ID,
+			PersonsToNotify:   userIDs,
+			Type:              escalationPolicyType,
+		}
+		err = p.do(ctx, "POST", p.url+escalationPoliciesEndpoint, policy, nil)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		position++
+	}
+
+	// Schedules responding together with the deployer are notified right
+	// after the deployer.
+	for _, r := range a.Responders {
+		if r.Type != assignment.ResponderSchedule {
+			continue
+		}
+
+		schedulePolicy := EscalationPolicy{
+			EscalationChainID:        chain.ID,
+			NotifyOnCallFromSchedule: r.Name,
+			Position:                 position,
+			Type:                     schedulePolicyType,
+		}
+		err = p.do(ctx, "POST", p.url+escalationPoliciesEndpoint, schedulePolicy, nil)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		position++
+	}
+	p.logger.Log("level", "debug", "message", fmt.Sprintf("escalation chain %#q for user %#q has been created", a.Name, a.User))
+
+	// The routing regex matches alert payloads mentioning both the repository
+	// and the environment, which mirrors the conditions of Opsgenie routing
+	// rules.
+	route := Route{
+		EscalationChainID: chain.ID,
+		IntegrationID:     p.integration,
+		RoutingRegex:      fmt.Sprintf("(?s)(?=.*%s)(?=.*%s)", regexp.QuoteMeta(a.Repository), regexp.QuoteMeta(a.Environment)),
+		RoutingType:       routingType,
+	}
+	err = p.do(ctx, "POST", p.url+routesEndpoint, route, &route)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	a.SetID(routeID, route.ID)
+	p.logger.Log("level", "debug", "message", fmt.Sprintf("route %#q for user %#q has been created", a.Name, a.User))
+
+	return nil
+}
+
+// extendOverride updates the duration of the override shifts named after the
+// assignment, so they end with the assignment.
+func (p *Provider) extendOverride(ctx context.Context, a assignment.Assignment) error {
+	shifts, err := p.shifts(ctx, a.Name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, shift := range shifts {
+		start, err := time.Parse(shiftTimeFormat, shift.Start)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		shift.Duration = int64(a.Expiry.Sub(start) / time.Second)
+		err = p.do(ctx, "PUT", p.url+fmt.Sprintf(onCallShiftEndpoint, shift.ID), shift, nil)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		p.logger.Log("level", "debug", "message", fmt.Sprintf("override %#q has been extended until %s", a.Name, a.Expiry.Format(time.RFC3339)))
+	}
+
+	return nil
+}
+
+func (p *Provider) deleteOverride(ctx context.Context, a assignment.Assignment) error {
+	shifts, err := p.shifts(ctx, a.Name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, shift := range shifts {
+		err = p.do(ctx, "DELETE", p.url+fmt.Sprintf(onCallShiftEndpoint, shift.ID), nil, nil)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		p.logger.Log("level", "debug", "message", fmt.Sprintf("override %#q has been deleted", a.Name))
+	}
+
+	return nil
+}
+
+// deleteRoute deletes the route and the escalation chain named after the
+// assignment. The route goes first, so alerts are never routed to a missing
+// escalation chain.
+func (p *Provider) deleteRoute(ctx context.Context, a assignment.Assignment) error {
+	chains, err := p.chains(ctx, a.Name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(chains) == 0 {
+		p.logger.Log("level", "debug", "message", fmt.Sprintf("escalation chain %#q does not exist anymore", a.Name))
+		return nil
+	}
+
+	next := p.url + routesEndpoint + "?integration_id=" + url.QueryEscape(p.integration)
+	for next != "" {
+		var list RouteList
+		err := p.do(ctx, "GET", next, nil, &list)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		for _, route := range list.Results {
+			for _, chain := range chains {
+				if route.EscalationChainID != chain.ID {
+					continue
+				}
+
+				err = p.do(ctx, "DELETE", p.url+fmt.Sprintf(routeEndpoint, route.ID), nil, nil)
+				if err != nil {
+					return microerror.Mask(err)
+				}
+				p.logger.Log("level", "debug", "message", fmt.Sprintf("route %#q has been deleted", a.Name))
+			}
+		}
+
+		next = list.Next
+	}
+
+	for _, chain := range chains {
+		err := p.do(ctx, "DELETE", p.url+fmt.Sprintf(escalationChainEndpoint, chain.ID), nil, nil)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		p.logger.Log("level", "debug", "message", fmt.Sprintf("escalation chain %#q has been deleted", a.Name))
+	}
+
+	return nil
+}
+
+// shifts returns the on-call shifts with the given name, or all managed
+// on-call shifts if the name is empty.
+func (p *Provider) shifts(ctx context.Context, name string) ([]OnCallShift, error) {
+	var shifts []OnCallShift
+
+	next := p.url + onCallShiftsEndpoint
+	if name != "" {
+		next += "?name=" + url.QueryEscape(name)
+	}
+	for next != "" {
+		var list OnCallShiftList
+		err := p.do(ctx, "GET", next, nil, &list)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, shift := range list.Results {
+			if shift.Name == name || name == "" && strings.HasPrefix(shift.Name, managedPrefix) {
+				shifts = append(shifts, shift)
+			}
+		}
+
+		next = list.Next
+	}
+
+	return shifts, nil
+}
+
+// scheduleShifts returns the managed on-call shifts of the schedule of the
+// provider owned by its team, so that overrides of other schedules and teams
+// in the organization are left alone.
+func (p *Provider) scheduleShifts(ctx context.Context) ([]OnCallShift, error) {
+	var schedule Schedule
+	err := p.do(ctx, "GET", p.url+fmt.Sprintf(schedulesEndpoint, p.schedule), nil, &schedule)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	inSchedule := map[string]bool{}
+	for _, id := range schedule.Shifts {
+		inSchedule[id] = true
+	}
+
+	shifts, err := p.shifts(ctx, "")
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var scheduleShifts []OnCallShift
+	for _, shift := range shifts {
+		if inSchedule[shift.ID] && shift.TeamID == p.team {
+			scheduleShifts = append(scheduleShifts, shift)
+		}
+	}
+
+	return scheduleShifts, nil
+}
+
+// chains returns the escalation chains with the given name, or all managed
+// escalation chains if the name is empty.
+func (p *Provider) chains(ctx context.Context, name string) ([]EscalationChain, error) {
+	var chains []EscalationChain
+
+	next := p.url + escalationChainsEndpoint
+	if name != "" {
+		next += "?name=" + url.QueryEscape(name)
+	}
+	for next != "" {
+		var list EscalationChainList
+		err := p.do(ctx, "GET", next, nil, &list)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, chain := range list.Results {
+			if chain.Name == name || name == "" && strings.HasPrefix(chain.Name, managedPrefix) {
+				chains = append(chains, chain)
+			}
+		}
+
+		next = list.Next
+	}
+
+	return chains, nil
+}
+
+// integrationChains returns the managed escalation chains of the team of the
+// provider routed to from its integration, so that escalation chains of
+// other integrations, teams and instances in the organization are left
+// alone.
+func (p *Provider) integrationChains(ctx context.Context) ([]EscalationChain, error) {
+	routed := map[string]bool{}
+	next := p.url + routesEndpoint + "?integration_id=" + url.QueryEscape(p.integration)
+	for next != "" {
+		var list RouteList
+		err := p.do(ctx, "GET", next, nil, &list)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, route := range list.Results {
+			routed[route.EscalationChainID] = true
+		}
+
+		next = list.Next
+	}
+
+	chains, err := p.chains(ctx, "")
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var integrationChains []EscalationChain
+	for _, chain := range chains {
+		if routed[chain.ID] && chain.TeamID == p.team {
+			integrationChains = append(integrationChains, chain)
+		}
+	}
+
+	return integrationChains, nil
+}
+
+// userID returns the Grafana OnCall user ID of the given username or email.
+// Resolved IDs are cached for the lifetime of the provider.
+func (p *Provider) userID(ctx context.Context, user string) (string, error) {
+	p.userLock.Lock()
+	defer p.userLock.Unlock()
+
+	if id, ok := p.userIDs[user]; ok {
+		return id, nil
+	}
+
+	next := p.url + usersEndpoint
+	for next != "" {
+		var list UserList
+		err := p.do(ctx, "GET", next, nil, &list)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+
+		for _, u := range list.Results {
+			if strings.EqualFold(u.Email, user) || u.Username == user {
+				p.userIDs[user] = u.ID
+				return u.ID, nil
+			}
+		}
+
+		next = list.Next
+	}
+
+	return "", microerror.Maskf(userNotFoundError, "%#q", user)
+}
+
+func (p *Provider) do(ctx context.Context, method, endpoint string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", p.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	operation := provider.Operation(method, endpoint, resources)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		provider.ObserveRequest(provider.Grafana, operation, 0)
+		return microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	provider.ObserveRequest(provider.Grafana, operation, resp.StatusCode)
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Objects to be deleted which do not exist anymore are deleted already.
+	if method == "DELETE" && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return microerror.Maskf(executionFailedError, "%s %s: expected 2xx, got %d", method, endpoint, resp.StatusCode)
+	}
+
+	if out != nil {
+		err = json.Unmarshal(b, out)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
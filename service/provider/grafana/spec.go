@@ -0,0 +1,74 @@
+package grafana
+
+// Types of the Grafana OnCall public API
+// (https://grafana.com/docs/oncall/latest/oncall-api-reference/).
+
+type EscalationChain struct {
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name"`
+	TeamID string `json:"team_id,omitempty"`
+}
+
+type EscalationChainList struct {
+	Next    string            `json:"next"`
+	Results []EscalationChain `json:"results"`
+}
+
+type EscalationPolicy struct {
+	ID                       string   `json:"id,omitempty"`
+	EscalationChainID        string   `json:"escalation_chain_id"`
+	NotifyOnCallFromSchedule string   `json:"notify_on_call_from_schedule,omitempty"`
+	PersonsToNotify          []string `json:"persons_to_notify,omitempty"`
+	Position                 int      `json:"position"`
+	Type                     string   `json:"type"`
+}
+
+type OnCallShift struct {
+	ID       string   `json:"id,omitempty"`
+	Duration int64    `json:"duration"`
+	Name     string   `json:"name"`
+	Start    string   `json:"start"`
+	TeamID   string   `json:"team_id,omitempty"`
+	TimeZone string   `json:"time_zone"`
+	Type     string   `json:"type"`
+	Users    []string `json:"users"`
+}
+
+type OnCallShiftList struct {
+	Next    string        `json:"next"`
+	Results []OnCallShift `json:"results"`
+}
+
+type Route struct {
+	ID                string `json:"id,omitempty"`
+	EscalationChainID string `json:"escalation_chain_id"`
+	IntegrationID     string `json:"integration_id"`
+	Position          int    `json:"position"`
+	RoutingRegex      string `json:"routing_regex"`
+	RoutingType       string `json:"routing_type"`
+}
+
+type RouteList struct {
+	Next    string  `json:"next"`
+	Results []Route `json:"results"`
+}
+
+// Schedule is a schedule. Updates only send the shifts, the other fields are
+// left out when empty, so they are never changed.
+type Schedule struct {
+	ID     string   `json:"id,omitempty"`
+	Name   string   `json:"name,omitempty"`
+	TeamID string   `json:"team_id,omitempty"`
+	Shifts []string `json:"shifts"`
+}
+
+type User struct {
+	ID       string `json:"id"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+type UserList struct {
+	Next    string `json:"next"`
+	Results []User `json:"results"`
+}
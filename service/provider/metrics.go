@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "auto_oncall"
+	subsystem = "provider"
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "requests_total",
+			Help:      "Number of requests sent to the on-call backend by provider, operation and status code.",
+		},
+		[]string{"provider", "operation", "code"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal)
+}
+
+// ObserveRequest counts a request sent to the backend of the given provider.
+// The code is the HTTP status code of the response, or zero if no response
+// was received.
+func ObserveRequest(provider, operation string, code int) {
+	c := "none"
+	if code > 0 {
+		c = strconv.Itoa(code)
+	}
+
+	requestsTotal.WithLabelValues(provider, operation, c).Inc()
+}
+
+// Operation names a request by its method and the last of the given
+// resources found in its path, e.g. post_routing_rules for a POST request to
+// /v2/teams/ops_team/routing-rules. Identifiers in the path are omitted, so
+// that the number of operations stays bounded.
+func Operation(method, endpoint string, resources []string) string {
+	resource := "unknown"
+
+	u, err := url.Parse(endpoint)
+	if err == nil {
+		for _, s := range strings.Split(u.Path, "/") {
+			for _, r := range resources {
+				if s == r {
+					resource = r
+				}
+			}
+		}
+	}
+
+	return strings.ToLower(method) + "_" + strings.Replace(resource, "-", "_", -1)
+}
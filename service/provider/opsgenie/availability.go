@@ -0,0 +1,82 @@
+package opsgenie
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/auto-oncall/service/availability"
+)
+
+const (
+	// Name is the name of the provider as availability source.
+	Name = "opsgenie"
+
+	forwardingRulesEndpoint = "/v2/forwarding-rules"
+	timelineEndpoint        = "/v2/schedules/%s/timeline?identifierType=name&expand=base&interval=1&intervalUnit=days&date=%s"
+
+	timeFormat = "2006-01-02 15:04 MST"
+)
+
+// Name returns Name.
+func (p *Provider) Name() string {
+	return Name
+}
+
+// Absence tells the subject absent while they forward their notifications to
+// someone else, or while their shift on the schedule of their team is taken
+// over by an override.
+func (p *Provider) Absence(ctx context.Context, s availability.Subject, t time.Time) (string, error) {
+	var rules ForwardingRuleList
+	err := p.do(ctx, "GET", forwardingRulesEndpoint, nil, &rules)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	for _, r := range rules.Data {
+		if r.FromUser.Username != s.User || t.Before(r.StartDate) || r.EndDate != nil && !t.Before(*r.EndDate) {
+			continue
+		}
+
+		if r.EndDate == nil {
+			return fmt.Sprintf("forwarding notifications to %s in Opsgenie", r.ToUser.Username), nil
+		}
+		return fmt.Sprintf("forwarding notifications to %s in Opsgenie until %s", r.ToUser.Username, r.EndDate.UTC().Format(timeFormat)), nil
+	}
+
+	if s.Schedule == "" {
+		return "", nil
+	}
+
+	var timeline Timeline
+	path := fmt.Sprintf(timelineEndpoint, url.PathEscape(s.Schedule), url.QueryEscape(t.UTC().Format(time.RFC3339)))
+	err = p.do(ctx, "GET", path, nil, &timeline)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	// The base timeline has the shifts of the rotations, the final timeline
+	// has them with overrides applied. A shift of the subject missing in the
+	// final timeline has been taken over by someone else.
+	if onCall(timeline.Data.BaseTimeline, s.User, t) && !onCall(timeline.Data.FinalTimeline, s.User, t) {
+		return fmt.Sprintf("overridden on schedule %#q in Opsgenie", s.Schedule), nil
+	}
+
+	return "", nil
+}
+
+// onCall tells whether the given user has a shift at the given point in time.
+func onCall(timeline TimelineRotations, user string, t time.Time) bool {
+	for _, r := range timeline.Rotations {
+		for _, p := range r.Periods {
+			if p.Recipient.Name == user && !t.Before(p.StartDate) && t.Before(p.EndDate) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
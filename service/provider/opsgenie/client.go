@@ -0,0 +1,77 @@
+package opsgenie
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/auto-oncall/service/provider"
+)
+
+const (
+	apiURL = "https://api.opsgenie.com"
+)
+
+// resources are the Opsgenie API resources requests are counted by.
+var resources = []string{"escalations", "forwarding-rules", "overrides", "routing-rules", "schedules", "teams", "timeline"}
+
+// do executes a request against the Opsgenie API. The given input is sent as
+// JSON body and the response body is decoded into out, if given.
+func (p *Provider) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	req, err := http.NewRequest(method, apiURL+path, bytes.NewReader(body))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", fmt.Sprintf("GenieKey %s", p.token.Value()))
+	req.Header.Set("Content-Type", "application/json")
+
+	operation := provider.Operation(method, path, resources)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		provider.ObserveRequest(provider.Opsgenie, operation, 0)
+		return microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	provider.ObserveRequest(provider.Opsgenie, operation, resp.StatusCode)
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if resp.StatusCode == http.StatusConflict {
+		return microerror.Maskf(alreadyExistsError, "%s %s", method, path)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return microerror.Maskf(notFoundError, "%s %s", method, path)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return microerror.Maskf(unexpectedResponseCodeError, "%s %s: expected 2xx, got %d", method, path, resp.StatusCode)
+	}
+
+	if out != nil {
+		err = json.Unmarshal(b, out)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package opsgenie
+
+import (
+	"bytes"
+	"io/ioutil"
+	"regexp"
+	"text/template"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+)
+
+var (
+	fields     = []string{"message", "alias", "description", "source", "entity", "tags", "actions", "details", "extra-properties", "recipients", "teams", "priority"}
+	operations = []string{"matches", "contains", "starts-with", "ends-with", "equals", "contains-key", "contains-value", "greater-than", "less-than", "is-empty", "equals-ignore-whitespace"}
+
+	templateFuncs = template.FuncMap{
+		"regexQuote": regexp.QuoteMeta,
+	}
+)
+
+// defaultConditions match alerts whose description contains the repository
+// and whose message contains the environment.
+var defaultConditions = []RoutingRuleCondition{
+	{
+		ExpectedValue: "{{ .Repository }}",
+		Field:         "description",
+		Operation:     "contains",
+	},
+	{
+		ExpectedValue: "{{ .Environment }}",
+		Field:         "message",
+		Operation:     "contains",
+	},
+}
+
+// RoutingRuleCondition is a condition of the routing rule created for an
+// assignment. ExpectedValue is a template rendered with the assignment, e.g.
+// "^{{ regexQuote .Repository }}$" for the matches operation. Key is
+// required for the extra-properties field and refers to the alert details
+// key.
+type RoutingRuleCondition struct {
+	ExpectedValue string
+	Field         string
+	Key           string
+	Not           bool
+	Operation     string
+}
+
+type condition struct {
+	RoutingRuleCondition
+	template *template.Template
+}
+
+// validateConditions checks the given conditions and parses their expected
+// value templates. Without conditions the default conditions are used.
+func validateConditions(conditions []RoutingRuleCondition) ([]condition, error) {
+	if len(conditions) == 0 {
+		conditions = defaultConditions
+	}
+
+	var parsed []condition
+	for _, c := range conditions {
+		if !contains(fields, c.Field) {
+			return nil, microerror.Maskf(invalidConfigError, "routing rule condition has invalid field %#q", c.Field)
+		}
+		if !contains(operations, c.Operation) {
+			return nil, microerror.Maskf(invalidConfigError, "routing rule condition has invalid operation %#q", c.Operation)
+		}
+		if c.Field == "extra-properties" && c.Key == "" {
+			return nil, microerror.Maskf(invalidConfigError, "routing rule condition on field %#q requires a key", c.Field)
+		}
+
+		// Executing the template against an empty assignment reveals references
+		// to unknown fields already at startup.
+		t, err := template.New(c.Field).Funcs(templateFuncs).Parse(c.ExpectedValue)
+		if err == nil {
+			err = t.Execute(ioutil.Discard, assignment.Assignment{})
+		}
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "routing rule condition has invalid expected value %#q: %s", c.ExpectedValue, err.Error())
+		}
+
+		parsed = append(parsed, condition{
+			RoutingRuleCondition: c,
+			template:             t,
+		})
+	}
+
+	return parsed, nil
+}
+
+// criteria renders the routing rule criteria for the given assignment.
+func (p *Provider) criteria(a assignment.Assignment) (Criteria, error) {
+	criteria := Criteria{
+		Type: routingRuleType,
+	}
+
+	for _, c := range p.conditions {
+		var expectedValue bytes.Buffer
+		err := c.template.Execute(&expectedValue, a)
+		if err != nil {
+			return Criteria{}, microerror.Mask(err)
+		}
+
+		if c.Operation == "matches" {
+			_, err = regexp.Compile(expectedValue.String())
+			if err != nil {
+				return Criteria{}, microerror.Maskf(invalidConfigError, "routing rule condition renders invalid regular expression %#q", expectedValue.String())
+			}
+		}
+
+		criteria.Conditions = append(criteria.Conditions, Condition{
+			ExpectedValue: expectedValue.String(),
+			Field:         c.Field,
+			Key:           c.Key,
+			Not:           c.Not,
+			Operation:     c.Operation,
+		})
+	}
+
+	return criteria, nil
+}
@@ -4,13 +4,13 @@ import (
 	"github.com/giantswarm/microerror"
 )
 
-var executionFailedError = &microerror.Error{
-	Kind: "executionFailedError",
+var alreadyExistsError = &microerror.Error{
+	Kind: "alreadyExistsError",
 }
 
-// IsExecutionFailed asserts executionFailedError.
-func IsExecutionFailed(err error) bool {
-	return microerror.Cause(err) == executionFailedError
+// IsAlreadyExists asserts alreadyExistsError.
+func IsAlreadyExists(err error) bool {
+	return microerror.Cause(err) == alreadyExistsError
 }
 
 var invalidConfigError = &microerror.Error{
@@ -22,29 +22,29 @@ func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
 
-var invalidTemplateError = &microerror.Error{
-	Kind: "invalidTemplateError",
+var unexpectedResponseCodeError = &microerror.Error{
+	Kind: "unexpectedResponseCodeError",
 }
 
-// IsInvalidTemplate asserts invalidTemplateError.
-func IsInvalidTemplate(err error) bool {
-	return microerror.Cause(err) == invalidTemplateError
+// IsUnexpectedResponseCode asserts unexpectedResponseCodeError.
+func IsUnexpectedResponseCode(err error) bool {
+	return microerror.Cause(err) == unexpectedResponseCodeError
 }
 
-var routingRuleDuplicationError = &microerror.Error{
-	Kind: "routingRuleDuplicationError",
+var notFoundError = &microerror.Error{
+	Kind: "notFoundError",
 }
 
-// IsRoutingRuleDuplication asserts routingRuleDuplication.
-func IsRoutingRuleDuplication(err error) bool {
-	return microerror.Cause(err) == routingRuleDuplicationError
+// IsNotFound asserts notFoundError.
+func IsNotFound(err error) bool {
+	return microerror.Cause(err) == notFoundError
 }
 
-var unexpectedResponseCodeError = &microerror.Error{
-	Kind: "unexpectedResponseCodeError",
+var routingRuleDuplicationError = &microerror.Error{
+	Kind: "routingRuleDuplicationError",
 }
 
-// IsUnexpectedResponseCode asserts unexpectedResponseCodeError.
-func IsUnexpectedResponseCode(err error) bool {
-	return microerror.Cause(err) == unexpectedResponseCodeError
+// IsRoutingRuleDuplication asserts routingRuleDuplicationError.
+func IsRoutingRuleDuplication(err error) bool {
+	return microerror.Cause(err) == routingRuleDuplicationError
 }
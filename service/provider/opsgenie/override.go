@@ -0,0 +1,245 @@
+package opsgenie
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+)
+
+const (
+	overridesEndpoint = "/v2/schedules/%s/overrides"
+	overrideEndpoint  = "/v2/schedules/%s/overrides/%s"
+
+	overrideID = "override/"
+
+	overrideAliasPrefix   = "auto-"
+	recipientTypeUser     = "user"
+	scheduleIdentifierArg = "?scheduleIdentifierType=name"
+)
+
+// createOverride creates overrides for the assigned user and its responders
+// on the schedule. The aliases of the overrides are recorded in the
+// assignment.
+func (p *Provider) createOverride(ctx context.Context, a *assignment.Assignment) error {
+	overrides, err := p.overrides(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, user := range append([]string{a.User}, userResponders(*a)...) {
+		alias, err := p.createUserOverride(ctx, *a, user, overrides)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		a.SetID(overrideID+user, alias)
+	}
+
+	return nil
+}
+
+// createUserOverride creates an override for the given user. When an override
+// created for an earlier deployment of the same user is still active, it is
+// extended instead, so overlapping deployments result in a single override.
+// It returns the alias of the override.
+func (p *Provider) createUserOverride(ctx context.Context, a assignment.Assignment, user string, overrides []Override) (string, error) {
+	now := time.Now().UTC()
+
+	for _, o := range overrides {
+		if o.User.Username != user || o.EndDate.Before(now) {
+			continue
+		}
+
+		if !o.EndDate.Before(a.Expiry) {
+			p.logger.Log("level", "debug", "message", fmt.Sprintf("override %#q for user %#q already covers %#q", o.Alias, user, a.Name))
+			return o.Alias, nil
+		}
+
+		err := p.updateOverride(ctx, o, a.Expiry)
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+		p.logger.Log("level", "debug", "message", fmt.Sprintf("override %#q for user %#q has been extended until %s for %#q", o.Alias, user, a.Expiry.Format(time.RFC3339), a.Name))
+
+		return o.Alias, nil
+	}
+
+	alias := a.Name
+	if user != a.User {
+		alias = fmt.Sprintf("%s-%s", a.Name, user)
+	}
+
+	override := Override{
+		Alias:     alias,
+		EndDate:   a.Expiry,
+		StartDate: now,
+		User: Recipient{
+			Type:     recipientTypeUser,
+			Username: user,
+		},
+	}
+	path := fmt.Sprintf(overridesEndpoint, url.PathEscape(p.schedule)) + scheduleIdentifierArg
+
+	err := p.do(ctx, "POST", path, override, nil)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	p.logger.Log("level", "debug", "message", fmt.Sprintf("override %#q for user %#q has been created on schedule %#q", alias, user, p.schedule))
+
+	return alias, nil
+}
+
+// deleteOverrides ends the active overrides of the assigned user and its
+// responders. Overrides are merged per user, so they may carry the name of an
+// earlier assignment and cover other assignments of the user. Such overrides
+// are shortened to the latest expiry of the other assignments, they are only
+// deleted when the user has no other assignment.
+func (p *Provider) deleteOverrides(ctx context.Context, a assignment.Assignment) error {
+	overrides, err := p.overrides(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	users := append([]string{a.User}, userResponders(a)...)
+
+	now := time.Now().UTC()
+	for _, o := range overrides {
+		if !contains(users, o.User.Username) || o.EndDate.Before(now) {
+			continue
+		}
+
+		until, ok := p.remainingExpiry(a, o.User.Username)
+		if !ok {
+			err = p.deleteOverride(ctx, o)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			continue
+		}
+		if !o.EndDate.After(until) {
+			continue
+		}
+
+		err = p.updateOverride(ctx, o, until)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		p.logger.Log("level", "debug", "message", fmt.Sprintf("override %#q for user %#q has been shortened until %s without %#q", o.Alias, o.User.Username, until.Format(time.RFC3339), a.Name))
+	}
+
+	return nil
+}
+
+// remainingExpiry returns the latest expiry of the active assignments other
+// than the given one putting the given user on call, if there are any.
+func (p *Provider) remainingExpiry(a assignment.Assignment, user string) (time.Time, bool) {
+	var until time.Time
+	for _, r := range p.registry.All() {
+		if r.Name == a.Name || (r.User != user && !contains(userResponders(r), user)) {
+			continue
+		}
+		if r.Expiry.After(until) {
+			until = r.Expiry
+		}
+	}
+
+	return until, !until.IsZero()
+}
+
+// updateOverride moves the end of the given override.
+func (p *Provider) updateOverride(ctx context.Context, o Override, end time.Time) error {
+	update := Override{
+		EndDate:   end,
+		StartDate: o.StartDate,
+		User:      o.User,
+	}
+	path := fmt.Sprintf(overrideEndpoint, url.PathEscape(p.schedule), url.PathEscape(o.Alias)) + scheduleIdentifierArg
+
+	err := p.do(ctx, "PUT", path, update, nil)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (p *Provider) deleteOverride(ctx context.Context, o Override) error {
+	path := fmt.Sprintf(overrideEndpoint, url.PathEscape(p.schedule), url.PathEscape(o.Alias)) + scheduleIdentifierArg
+
+	err := p.do(ctx, "DELETE", path, nil, nil)
+	if IsNotFound(err) {
+		p.logger.Log("level", "debug", "message", fmt.Sprintf("override %#q does not exist anymore", o.Alias))
+	} else if err != nil {
+		return microerror.Mask(err)
+	} else {
+		p.logger.Log("level", "debug", "message", fmt.Sprintf("override %#q for user %#q has been deleted", o.Alias, o.User.Username))
+	}
+
+	return nil
+}
+
+// reconcileOverrides creates or extends the overrides of the given
+// assignments and deletes active managed overrides of users not assigned
+// anymore.
+func (p *Provider) reconcileOverrides(ctx context.Context, active []assignment.Assignment) ([]assignment.Assignment, error) {
+	overrides, err := p.overrides(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var users []string
+	for _, a := range active {
+		users = append(users, a.User)
+		users = append(users, userResponders(a)...)
+	}
+
+	now := time.Now().UTC()
+	for _, o := range overrides {
+		if contains(users, o.User.Username) || o.EndDate.Before(now) {
+			continue
+		}
+
+		p.logger.Log("level", "info", "message", fmt.Sprintf("deleting orphaned override %#q", o.Alias))
+
+		err = p.deleteOverride(ctx, o)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	for i := range active {
+		err = p.createOverride(ctx, &active[i])
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return active, nil
+}
+
+// overrides returns the overrides of the schedule created by this service.
+func (p *Provider) overrides(ctx context.Context) ([]Override, error) {
+	var list OverrideList
+	{
+		path := fmt.Sprintf(overridesEndpoint, url.PathEscape(p.schedule)) + scheduleIdentifierArg
+
+		err := p.do(ctx, "GET", path, nil, &list)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var overrides []Override
+	for _, o := range list.Data {
+		if strings.HasPrefix(o.Alias, overrideAliasPrefix) {
+			overrides = append(overrides, o)
+		}
+	}
+
+	return overrides, nil
+}
@@ -0,0 +1,287 @@
+package opsgenie
+
+import (
+	"path"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+)
+
+const (
+	// DefaultPolicy is the name of the policy used when no selector matches
+	// an assignment. Unless configured otherwise it notifies the deployer
+	// after one minute if the alert is not acknowledged, repeating every five
+	// minutes up to 20 times.
+	DefaultPolicy = "default"
+
+	// RecipientDeployer is the recipient type resolved to the user of an
+	// assignment.
+	RecipientDeployer = "deployer"
+	// RecipientSchedule is the recipient type of an Opsgenie schedule.
+	RecipientSchedule = "schedule"
+	// RecipientTeam is the recipient type of an Opsgenie team.
+	RecipientTeam = "team"
+	// RecipientUser is the recipient type of a fixed Opsgenie user.
+	RecipientUser = "user"
+)
+
+var (
+	conditions  = []string{"if-not-acked", "if-not-closed"}
+	days        = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+	notifyTypes = []string{"default", "next", "previous", "users", "admins", "all"}
+)
+
+var defaultPolicy = Policy{
+	Name: DefaultPolicy,
+	Rules: []PolicyRule{
+		{
+			Condition:  "if-not-acked",
+			Delay:      1,
+			NotifyType: "default",
+			Recipient: PolicyRecipient{
+				Type: RecipientDeployer,
+			},
+		},
+	},
+	Repeat: &Repeat{
+		Count:        20,
+		WaitInterval: 5,
+	},
+}
+
+// Policy describes the shape of the escalation created for an assignment.
+type Policy struct {
+	Name  string
+	Rules []PolicyRule
+	// Repeat is optional. Without it the escalation is not repeated.
+	Repeat *Repeat
+	// TimeRestriction optionally limits when the routing rule of the
+	// assignment applies, e.g. to working hours only.
+	TimeRestriction *TimeRestriction
+}
+
+// PolicyRule is a single escalation step. Delay is given in minutes.
+type PolicyRule struct {
+	Condition  string
+	Delay      int
+	NotifyType string
+	Recipient  PolicyRecipient
+}
+
+// PolicyRecipient is the recipient of an escalation step. Name is ignored for
+// type deployer and refers to the username, schedule or team name otherwise.
+type PolicyRecipient struct {
+	Name string
+	Type string
+}
+
+// PolicySelector selects a policy for assignments whose repository and
+// environment match the given shell patterns. Empty patterns match
+// everything.
+type PolicySelector struct {
+	Environment string
+	Policy      string
+	Repository  string
+}
+
+// validatePolicies checks the given policies and selectors, fills in
+// defaults and returns the policies by name. The built-in default policy is
+// added unless a policy named DefaultPolicy is configured.
+func validatePolicies(policies []Policy, selectors []PolicySelector) (map[string]Policy, error) {
+	byName := map[string]Policy{
+		DefaultPolicy: defaultPolicy,
+	}
+
+	configured := map[string]bool{}
+	for _, p := range policies {
+		if p.Name == "" {
+			return nil, microerror.Maskf(invalidConfigError, "escalation policy name must not be empty")
+		}
+		if configured[p.Name] {
+			return nil, microerror.Maskf(invalidConfigError, "escalation policy %#q must not be defined twice", p.Name)
+		}
+		if len(p.Rules) == 0 {
+			return nil, microerror.Maskf(invalidConfigError, "escalation policy %#q must have rules", p.Name)
+		}
+
+		for i, r := range p.Rules {
+			if r.Condition == "" {
+				r.Condition = "if-not-acked"
+			}
+			if r.NotifyType == "" {
+				r.NotifyType = "default"
+			}
+
+			if !contains(conditions, r.Condition) {
+				return nil, microerror.Maskf(invalidConfigError, "escalation policy %#q has invalid condition %#q", p.Name, r.Condition)
+			}
+			if !contains(notifyTypes, r.NotifyType) {
+				return nil, microerror.Maskf(invalidConfigError, "escalation policy %#q has invalid notify type %#q", p.Name, r.NotifyType)
+			}
+			if r.Delay < 0 {
+				return nil, microerror.Maskf(invalidConfigError, "escalation policy %#q has negative delay", p.Name)
+			}
+
+			switch r.Recipient.Type {
+			case RecipientDeployer:
+			case RecipientSchedule, RecipientTeam, RecipientUser:
+				if r.Recipient.Name == "" {
+					return nil, microerror.Maskf(invalidConfigError, "escalation policy %#q has %#q recipient without name", p.Name, r.Recipient.Type)
+				}
+			default:
+				return nil, microerror.Maskf(invalidConfigError, "escalation policy %#q has invalid recipient type %#q", p.Name, r.Recipient.Type)
+			}
+
+			p.Rules[i] = r
+		}
+
+		if p.Repeat != nil && (p.Repeat.Count < 0 || p.Repeat.WaitInterval < 0) {
+			return nil, microerror.Maskf(invalidConfigError, "escalation policy %#q has negative repeat settings", p.Name)
+		}
+
+		err := validateTimeRestriction(p.Name, p.TimeRestriction)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		configured[p.Name] = true
+		byName[p.Name] = p
+	}
+
+	for _, s := range selectors {
+		if _, ok := byName[s.Policy]; !ok {
+			return nil, microerror.Maskf(invalidConfigError, "escalation policy selector refers to unknown policy %#q", s.Policy)
+		}
+		for _, pattern := range []string{s.Environment, s.Repository} {
+			_, err := path.Match(pattern, "")
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "escalation policy selector has invalid pattern %#q", pattern)
+			}
+		}
+	}
+
+	return byName, nil
+}
+
+func validateTimeRestriction(name string, t *TimeRestriction) error {
+	if t == nil {
+		return nil
+	}
+
+	var restrictions []Restriction
+	switch t.Type {
+	case "time-of-day":
+		if t.Restriction == nil {
+			return microerror.Maskf(invalidConfigError, "escalation policy %#q has time restriction of type %#q without restriction", name, t.Type)
+		}
+		restrictions = []Restriction{*t.Restriction}
+	case "weekday-and-time-of-day":
+		if len(t.Restrictions) == 0 {
+			return microerror.Maskf(invalidConfigError, "escalation policy %#q has time restriction of type %#q without restrictions", name, t.Type)
+		}
+		for _, r := range t.Restrictions {
+			if !contains(days, r.StartDay) || !contains(days, r.EndDay) {
+				return microerror.Maskf(invalidConfigError, "escalation policy %#q has time restriction with invalid days %#q and %#q", name, r.StartDay, r.EndDay)
+			}
+		}
+		restrictions = t.Restrictions
+	default:
+		return microerror.Maskf(invalidConfigError, "escalation policy %#q has time restriction with invalid type %#q", name, t.Type)
+	}
+
+	for _, r := range restrictions {
+		if r.StartHour < 0 || r.StartHour > 23 || r.EndHour < 0 || r.EndHour > 23 || r.StartMin < 0 || r.StartMin > 59 || r.EndMin < 0 || r.EndMin > 59 {
+			return microerror.Maskf(invalidConfigError, "escalation policy %#q has time restriction with invalid hours or minutes", name)
+		}
+	}
+
+	return nil
+}
+
+// policy returns the policy of the first selector matching the assignment,
+// or the default policy.
+func (p *Provider) policy(a assignment.Assignment) Policy {
+	for _, s := range p.selectors {
+		if match(s.Repository, a.Repository) && match(s.Environment, a.Environment) {
+			return p.policies[s.Policy]
+		}
+	}
+
+	return p.policies[DefaultPolicy]
+}
+
+// escalation renders the escalation of the given policy for the assignment.
+func (p *Provider) escalation(policy Policy, a assignment.Assignment) Escalation {
+	e := Escalation{
+		Name: a.Name,
+		OwnerTeam: &Team{
+			Name: p.teamOf(a),
+		},
+		Repeat: policy.Repeat,
+	}
+
+	for _, r := range policy.Rules {
+		var recipients []Recipient
+		switch r.Recipient.Type {
+		case RecipientDeployer:
+			// Responders are notified in every step the deployer is notified in.
+			for _, u := range append([]string{a.User}, userResponders(a)...) {
+				if u != "" {
+					recipients = append(recipients, Recipient{Type: RecipientUser, Username: u})
+				}
+			}
+			for _, r := range a.Responders {
+				if r.Type == assignment.ResponderSchedule {
+					recipients = append(recipients, Recipient{Type: RecipientSchedule, Name: r.Name})
+				}
+			}
+		case RecipientUser:
+			recipients = append(recipients, Recipient{Type: RecipientUser, Username: r.Recipient.Name})
+		default:
+			recipients = append(recipients, Recipient{Type: r.Recipient.Type, Name: r.Recipient.Name})
+		}
+
+		for _, recipient := range recipients {
+			e.Rules = append(e.Rules, EscalationRule{
+				Condition:  r.Condition,
+				Delay:      Delay{TimeAmount: r.Delay},
+				NotifyType: r.NotifyType,
+				Recipient:  recipient,
+			})
+		}
+	}
+
+	return e
+}
+
+// userResponders returns the users responding together with the deployer.
+func userResponders(a assignment.Assignment) []string {
+	var users []string
+	for _, r := range a.Responders {
+		if r.Type == assignment.ResponderUser {
+			users = append(users, r.Name)
+		}
+	}
+
+	return users
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func match(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	ok, _ := path.Match(pattern, s)
+	return ok
+}
@@ -0,0 +1,249 @@
+// Package opsgenie implements the Opsgenie provider. Depending on its mode an
+// assignment becomes either a dedicated escalation and team routing rule, or
+// a time-boxed override on a schedule.
+package opsgenie
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/secret"
+)
+
+const (
+	// ModeOverride creates an override for the deployer on the configured
+	// schedule.
+	ModeOverride = "override"
+	// ModeRoutingRule creates an escalation notifying the deployer and a team
+	// routing rule forwarding matching alerts to it.
+	ModeRoutingRule = "routingrule"
+)
+
+const (
+	scheduleEndpoint = "/v2/schedules/%s?identifierType=name"
+	teamEndpoint     = "/v2/teams/%s?identifierType=name"
+)
+
+type Config struct {
+	HttpClient *http.Client
+	Logger     micrologger.Logger
+
+	// Conditions are the conditions of routing rules. When empty, alerts are
+	// matched by their description containing the repository and their
+	// message containing the environment.
+	Conditions []RoutingRuleCondition
+	// Mode is either ModeOverride or ModeRoutingRule.
+	Mode string
+	// Order is the order of routing rules within the team.
+	Order int
+	// Policies are the escalation policies available in routing rule mode in
+	// addition to the built-in DefaultPolicy.
+	Policies []Policy
+	// PolicySelectors select the escalation policy of an assignment. The
+	// first matching selector wins.
+	PolicySelectors []PolicySelector
+	// Registry keeps track of the assignments. It is needed in override
+	// mode, where overrides are merged per user across assignments.
+	Registry *assignment.Registry
+	// Schedule is the name of the schedule overrides are created on in
+	// override mode.
+	Schedule string
+	// Team is the name of the team owning escalations and routing rules.
+	Team string
+	// Teams are the names of further teams owning escalations and routing
+	// rules of assignments, e.g. those of GitHub organizations.
+	Teams []string
+	// Token is the API token. It may be read from a file and change while
+	// running.
+	Token *secret.Secret
+}
+
+type Provider struct {
+	httpClient *http.Client
+	logger     micrologger.Logger
+
+	conditions []condition
+	mode       string
+	order      int
+	policies   map[string]Policy
+	registry   *assignment.Registry
+	schedule   string
+	selectors  []PolicySelector
+	team       string
+	teams      []string
+	token      *secret.Secret
+}
+
+func New(config Config) (*Provider, error) {
+	if config.HttpClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.HttpClient must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	if config.Order < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Order must not be negative", config)
+	}
+	if config.Team == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Team must not be empty", config)
+	}
+	if config.Token.Value() == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Token must not be empty", config)
+	}
+
+	switch config.Mode {
+	case ModeOverride:
+		if config.Registry == nil {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Registry must not be empty in %#q mode", config, config.Mode)
+		}
+		if config.Schedule == "" {
+			return nil, microerror.Maskf(invalidConfigError, "%T.Schedule must not be empty in %#q mode", config, config.Mode)
+		}
+	case ModeRoutingRule:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "%T.Mode must be %#q or %#q, got %#q", config, ModeOverride, ModeRoutingRule, config.Mode)
+	}
+
+	policies, err := validatePolicies(config.Policies, config.PolicySelectors)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	conditions, err := validateConditions(config.Conditions)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	teams := []string{config.Team}
+	for _, t := range config.Teams {
+		if !contains(teams, t) {
+			teams = append(teams, t)
+		}
+	}
+
+	p := &Provider{
+		httpClient: config.HttpClient,
+		logger:     config.Logger,
+
+		conditions: conditions,
+		mode:       config.Mode,
+		order:      config.Order,
+		policies:   policies,
+		registry:   config.Registry,
+		schedule:   config.Schedule,
+		selectors:  config.PolicySelectors,
+		team:       config.Team,
+		teams:      teams,
+		token:      config.Token,
+	}
+
+	return p, nil
+}
+
+// Check verifies that the API token is valid and the configured teams exist,
+// and in override mode that the configured schedule exists.
+func (p *Provider) Check() error {
+	ctx := context.Background()
+
+	for _, t := range p.teams {
+		err := p.do(ctx, "GET", fmt.Sprintf(teamEndpoint, url.PathEscape(t)), nil, nil)
+		if IsNotFound(err) {
+			return microerror.Maskf(notFoundError, "team %#q", t)
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if p.mode == ModeOverride {
+		err := p.do(ctx, "GET", fmt.Sprintf(scheduleEndpoint, url.PathEscape(p.schedule)), nil, nil)
+		if IsNotFound(err) {
+			return microerror.Maskf(notFoundError, "schedule %#q", p.schedule)
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// Create puts the assigned user on call, depending on the mode either by an
+// escalation and routing rule or by a schedule override.
+func (p *Provider) Create(ctx context.Context, a *assignment.Assignment) error {
+	var err error
+
+	if p.mode == ModeOverride {
+		err = p.createOverride(ctx, a)
+	} else {
+		err = p.createRoutingRule(ctx, a)
+	}
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Extend extends the overrides of the given assignment. Escalations and
+// routing rules are not bound in time, they stay until they are deleted.
+func (p *Provider) Extend(ctx context.Context, a assignment.Assignment) error {
+	if p.mode == ModeOverride {
+		err := p.createOverride(ctx, &a)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// Delete removes the escalation and routing rule, or the overrides, of the
+// given assignment.
+func (p *Provider) Delete(ctx context.Context, a assignment.Assignment) error {
+	var err error
+
+	if p.mode == ModeOverride {
+		err = p.deleteOverrides(ctx, a)
+	} else {
+		err = p.deleteRoutingRule(ctx, a)
+	}
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// teamOf returns the team owning the escalation and routing rule of the
+// given assignment.
+func (p *Provider) teamOf(a assignment.Assignment) string {
+	if a.Team == "" {
+		return p.team
+	}
+
+	return a.Team
+}
+
+// Reconcile recreates the escalations and routing rules, or the overrides, of
+// the given assignments and deletes managed ones not belonging to any of
+// them.
+func (p *Provider) Reconcile(ctx context.Context, active []assignment.Assignment) ([]assignment.Assignment, error) {
+	var err error
+
+	if p.mode == ModeOverride {
+		active, err = p.reconcileOverrides(ctx, active)
+	} else {
+		active, err = p.reconcileRoutingRules(ctx, active)
+	}
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return active, nil
+}
@@ -0,0 +1,186 @@
+package opsgenie
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+)
+
+const (
+	escalationEndpoint   = "/v2/escalations/%s?identifierType=name"
+	escalationsEndpoint  = "/v2/escalations"
+	routingRuleEndpoint  = "/v2/teams/%s/routing-rules/%s?teamIdentifierType=name"
+	routingRulesEndpoint = "/v2/teams/%s/routing-rules?teamIdentifierType=name"
+
+	escalationID  = "escalation"
+	routingRuleID = "routingrule"
+
+	managedPrefix        = "auto-"
+	notifyTypeEscalation = "escalation"
+	routingRuleType      = "match-all-conditions"
+)
+
+// createRoutingRule creates an escalation rendered from the policy selected
+// for the assignment and a team routing rule forwarding alerts matching the
+// configured conditions to it. The IDs of both are recorded in the
+// assignment.
+func (p *Provider) createRoutingRule(ctx context.Context, a *assignment.Assignment) error {
+	policy := p.policy(*a)
+
+	var result Result
+	escalation := p.escalation(policy, *a)
+	err := p.do(ctx, "POST", escalationsEndpoint, escalation, &result)
+	if IsAlreadyExists(err) {
+		// An escalation with this name already exists, which is the desired
+		// state already.
+		p.logger.Log("level", "debug", "message", fmt.Sprintf("escalation %#q already exists", a.Name))
+
+		err = p.do(ctx, "GET", fmt.Sprintf(escalationEndpoint, url.PathEscape(a.Name)), nil, &result)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	} else if err != nil {
+		return microerror.Mask(err)
+	} else {
+		p.logger.Log("level", "debug", "message", fmt.Sprintf("escalation %#q for user %#q has been created using policy %#q", a.Name, a.User, policy.Name))
+	}
+	a.SetID(escalationID, result.Data.ID)
+
+	routingRulesPath := fmt.Sprintf(routingRulesEndpoint, url.PathEscape(p.teamOf(*a)))
+
+	var routingRules RoutingRuleList
+	err = p.do(ctx, "GET", routingRulesPath, nil, &routingRules)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	for _, r := range routingRules.Data {
+		if r.Name == a.Name {
+			return microerror.Maskf(routingRuleDuplicationError, "routing rule with name %#q already exists", a.Name)
+		}
+	}
+
+	criteria, err := p.criteria(*a)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	routingRule := RoutingRule{
+		Criteria: criteria,
+		Name:     a.Name,
+		Notify: Notify{
+			Name: a.Name,
+			Type: notifyTypeEscalation,
+		},
+		Order:           p.order,
+		TimeRestriction: policy.TimeRestriction,
+	}
+	err = p.do(ctx, "POST", routingRulesPath, routingRule, &result)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	a.SetID(routingRuleID, result.Data.ID)
+	p.logger.Log("level", "debug", "message", fmt.Sprintf("routing rule %#q for user %#q has been created", a.Name, a.User))
+
+	return nil
+}
+
+// deleteRoutingRule deletes the routing rule and escalation of the given
+// assignment. The routing rule goes first, so alerts are never routed to a
+// missing escalation.
+func (p *Provider) deleteRoutingRule(ctx context.Context, a assignment.Assignment) error {
+	team := p.teamOf(a)
+
+	var routingRules RoutingRuleList
+	err := p.do(ctx, "GET", fmt.Sprintf(routingRulesEndpoint, url.PathEscape(team)), nil, &routingRules)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	for _, r := range routingRules.Data {
+		if r.Name != a.Name {
+			continue
+		}
+
+		err = p.do(ctx, "DELETE", fmt.Sprintf(routingRuleEndpoint, url.PathEscape(team), url.PathEscape(r.ID)), nil, nil)
+		if IsNotFound(err) {
+			p.logger.Log("level", "debug", "message", fmt.Sprintf("routing rule %#q does not exist anymore", a.Name))
+		} else if err != nil {
+			return microerror.Mask(err)
+		} else {
+			p.logger.Log("level", "debug", "message", fmt.Sprintf("routing rule %#q has been deleted", a.Name))
+		}
+	}
+
+	err = p.do(ctx, "DELETE", fmt.Sprintf(escalationEndpoint, url.PathEscape(a.Name)), nil, nil)
+	if IsNotFound(err) {
+		p.logger.Log("level", "debug", "message", fmt.Sprintf("escalation %#q does not exist anymore", a.Name))
+	} else if err != nil {
+		return microerror.Mask(err)
+	} else {
+		p.logger.Log("level", "debug", "message", fmt.Sprintf("escalation %#q has been deleted", a.Name))
+	}
+
+	return nil
+}
+
+// reconcileRoutingRules creates the routing rules and escalations missing for
+// the given assignments and deletes managed routing rules and escalations not
+// belonging to any of them, in all configured teams.
+func (p *Provider) reconcileRoutingRules(ctx context.Context, active []assignment.Assignment) ([]assignment.Assignment, error) {
+	// Routing rules are identified by team and name, so that a rule moved to
+	// another team is recreated in the right one.
+	type key struct {
+		team string
+		name string
+	}
+
+	names := map[key]bool{}
+	for _, a := range active {
+		names[key{team: p.teamOf(a), name: a.Name}] = true
+	}
+
+	existing := map[key]bool{}
+	for _, team := range p.teams {
+		var routingRules RoutingRuleList
+		err := p.do(ctx, "GET", fmt.Sprintf(routingRulesEndpoint, url.PathEscape(team)), nil, &routingRules)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, r := range routingRules.Data {
+			if !strings.HasPrefix(r.Name, managedPrefix) {
+				continue
+			}
+			k := key{team: team, name: r.Name}
+			existing[k] = true
+
+			if !names[k] {
+				p.logger.Log("level", "info", "message", fmt.Sprintf("deleting orphaned routing rule %#q of team %#q", r.Name, team))
+
+				err = p.deleteRoutingRule(ctx, assignment.Assignment{Name: r.Name, Team: team})
+				if err != nil {
+					return nil, microerror.Mask(err)
+				}
+			}
+		}
+	}
+
+	for i := range active {
+		if existing[key{team: p.teamOf(active[i]), name: active[i].Name}] {
+			continue
+		}
+
+		p.logger.Log("level", "info", "message", fmt.Sprintf("recreating missing routing rule %#q", active[i].Name))
+
+		err := p.createRoutingRule(ctx, &active[i])
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return active, nil
+}
@@ -0,0 +1,160 @@
+package opsgenie
+
+import (
+	"time"
+)
+
+// Types of the Opsgenie escalation API
+// (https://docs.opsgenie.com/docs/escalation-api).
+
+type Delay struct {
+	TimeAmount int `json:"timeAmount"`
+}
+
+type Escalation struct {
+	ID        string           `json:"id,omitempty"`
+	Name      string           `json:"name"`
+	OwnerTeam *Team            `json:"ownerTeam,omitempty"`
+	Repeat    *Repeat          `json:"repeat,omitempty"`
+	Rules     []EscalationRule `json:"rules"`
+}
+
+type EscalationRule struct {
+	Condition  string    `json:"condition"`
+	Delay      Delay     `json:"delay"`
+	NotifyType string    `json:"notifyType"`
+	Recipient  Recipient `json:"recipient"`
+}
+
+type Repeat struct {
+	CloseAlertAfterAll   bool `json:"closeAlertAfterAll"`
+	Count                int  `json:"count"`
+	ResetRecipientStates bool `json:"resetRecipientStates"`
+	WaitInterval         int  `json:"waitInterval"`
+}
+
+type Team struct {
+	Name string `json:"name"`
+}
+
+// Result is the response of requests creating or reading a single object.
+type Result struct {
+	Data struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"data"`
+}
+
+// Types of the Opsgenie team routing rule API
+// (https://docs.opsgenie.com/docs/team-routing-rule-api).
+
+type Condition struct {
+	ExpectedValue string `json:"expectedValue"`
+	Field         string `json:"field"`
+	Key           string `json:"key,omitempty"`
+	Not           bool   `json:"not"`
+	Operation     string `json:"operation"`
+}
+
+type Criteria struct {
+	Conditions []Condition `json:"conditions"`
+	Type       string      `json:"type"`
+}
+
+type Notify struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type RoutingRule struct {
+	ID              string           `json:"id,omitempty"`
+	Criteria        Criteria         `json:"criteria"`
+	Name            string           `json:"name"`
+	Notify          Notify           `json:"notify"`
+	Order           int              `json:"order"`
+	TimeRestriction *TimeRestriction `json:"timeRestriction,omitempty"`
+}
+
+type RoutingRuleList struct {
+	Data []RoutingRule `json:"data"`
+}
+
+// Restriction is a time range of a time restriction. StartDay and EndDay are
+// only set for restrictions of type weekday-and-time-of-day.
+type Restriction struct {
+	EndDay    string `json:"endDay,omitempty"`
+	EndHour   int    `json:"endHour"`
+	EndMin    int    `json:"endMin"`
+	StartDay  string `json:"startDay,omitempty"`
+	StartHour int    `json:"startHour"`
+	StartMin  int    `json:"startMin"`
+}
+
+// TimeRestriction limits when a routing rule applies. Restriction is used for
+// type time-of-day, Restrictions for type weekday-and-time-of-day.
+type TimeRestriction struct {
+	Restriction  *Restriction  `json:"restriction,omitempty"`
+	Restrictions []Restriction `json:"restrictions,omitempty"`
+	Type         string        `json:"type"`
+}
+
+// Types of the Opsgenie schedule override API
+// (https://docs.opsgenie.com/docs/schedule-override-api).
+
+type Override struct {
+	Alias     string    `json:"alias,omitempty"`
+	EndDate   time.Time `json:"endDate"`
+	StartDate time.Time `json:"startDate"`
+	User      Recipient `json:"user"`
+}
+
+type OverrideList struct {
+	Data []Override `json:"data"`
+}
+
+type Recipient struct {
+	Name     string `json:"name,omitempty"`
+	Type     string `json:"type"`
+	Username string `json:"username,omitempty"`
+}
+
+// Types of the Opsgenie forwarding rule API
+// (https://docs.opsgenie.com/docs/forwarding-rule-api).
+
+type ForwardingRule struct {
+	Alias     string     `json:"alias,omitempty"`
+	EndDate   *time.Time `json:"endDate,omitempty"`
+	FromUser  Recipient  `json:"fromUser"`
+	StartDate time.Time  `json:"startDate"`
+	ToUser    Recipient  `json:"toUser"`
+}
+
+type ForwardingRuleList struct {
+	Data []ForwardingRule `json:"data"`
+}
+
+// Types of the Opsgenie schedule timeline API
+// (https://docs.opsgenie.com/docs/schedule-api#get-schedule-timeline).
+
+type Timeline struct {
+	Data struct {
+		BaseTimeline  TimelineRotations `json:"baseTimeline"`
+		FinalTimeline TimelineRotations `json:"finalTimeline"`
+	} `json:"data"`
+}
+
+type TimelineRotations struct {
+	Rotations []TimelineRotation `json:"rotations"`
+}
+
+type TimelineRotation struct {
+	Name    string           `json:"name"`
+	Periods []TimelinePeriod `json:"periods"`
+}
+
+type TimelinePeriod struct {
+	EndDate   time.Time `json:"endDate"`
+	Recipient Recipient `json:"recipient"`
+	StartDate time.Time `json:"startDate"`
+	Type      string    `json:"type"`
+}
@@ -0,0 +1,43 @@
+// Package provider defines the interface implemented by on-call backends.
+package provider
+
+import (
+	"context"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+)
+
+const (
+	// Alertmanager is the name of the Prometheus Alertmanager provider.
+	Alertmanager = "alertmanager"
+	// Grafana is the name of the Grafana OnCall provider.
+	Grafana = "grafana"
+	// Opsgenie is the name of the Opsgenie provider.
+	Opsgenie = "opsgenie"
+)
+
+// Provider turns on-call assignments into constructs of a paging backend.
+type Provider interface {
+	// Create creates everything needed in the backend to page the assigned
+	// user for alerts matching the assignment until it expires. The IDs of
+	// created objects are recorded in the assignment.
+	Create(ctx context.Context, a *assignment.Assignment) error
+	// Extend moves the end of an existing assignment to its new expiry. The
+	// name of the assignment stays the same.
+	Extend(ctx context.Context, a assignment.Assignment) error
+	// Delete removes everything created in the backend for the given
+	// assignment. Objects already gone are not considered an error.
+	Delete(ctx context.Context, a assignment.Assignment) error
+	// Reconcile makes the backend match the given active assignments. Missing
+	// objects are recreated and managed objects not belonging to any of the
+	// assignments are deleted. It returns the assignments with the IDs of
+	// their objects updated.
+	Reconcile(ctx context.Context, active []assignment.Assignment) ([]assignment.Assignment, error)
+}
+
+// Checker is implemented by providers able to verify that the backend is
+// reachable and the configured credentials are valid.
+type Checker interface {
+	// Check returns an error if the backend can not be used.
+	Check() error
+}
@@ -0,0 +1,156 @@
+// Package secret provides secrets like API tokens, given in the configuration
+// or read from their own file, e.g. a projected Kubernetes secret or a Vault
+// agent sink. Files are read again when they change, so that secrets can be
+// rotated without restart.
+package secret
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	// maxSize is the size of secret files read at most.
+	maxSize = 64 << 10
+	// redacted is shown instead of the value when a secret is formatted.
+	redacted = "<redacted>"
+)
+
+// Config represents the configuration used to create a new secret.
+type Config struct {
+	Logger micrologger.Logger
+
+	// Name describes the secret in logs and errors, e.g. GitHub token. The
+	// value itself never appears in either.
+	Name string
+	// Path is the file the secret is read from. Surrounding whitespace, e.g.
+	// a trailing newline, is removed. Value is used when empty.
+	Path string
+	// Value is the secret given in the configuration, e.g. by flag, config
+	// file or environment variable.
+	Value string
+}
+
+// Secret is a secret value. Values read from a file are read again when its
+// modification time or size changes, the previous value is kept while the
+// file cannot be read or is empty.
+type Secret struct {
+	logger micrologger.Logger
+
+	name string
+	path string
+
+	mutex   sync.Mutex
+	value   string
+	modTime time.Time
+	size    int64
+	// failing tells whether reading the file failed last time, so that
+	// failures are logged once and not on every use of the secret.
+	failing bool
+}
+
+// New creates a new secret. A secret read from a file fails to be created
+// when the file cannot be read.
+func New(config Config) (*Secret, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+	if config.Name == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Name must not be empty", config)
+	}
+
+	s := &Secret{
+		logger: config.Logger,
+
+		name: config.Name,
+		path: config.Path,
+	}
+
+	if s.path == "" {
+		s.value = config.Value
+	} else {
+		err := s.read()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	return s, nil
+}
+
+// Value returns the current value of the secret, empty if not configured.
+func (s *Secret) Value() string {
+	if s == nil {
+		return ""
+	}
+	if s.path == "" {
+		return s.value
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.read()
+	if err != nil {
+		if !s.failing {
+			s.logger.Log("level", "warning", "message", fmt.Sprintf("reading %s from file %#q failed, using previous value", s.name, s.path), "stack", fmt.Sprintf("%#v", err))
+		}
+		s.failing = true
+	} else {
+		s.failing = false
+	}
+
+	return s.value
+}
+
+// String returns a placeholder, so that formatting a secret, e.g. as part of
+// a configuration, does not reveal it.
+func (s *Secret) String() string {
+	return redacted
+}
+
+// GoString returns a placeholder like String.
+func (s *Secret) GoString() string {
+	return redacted
+}
+
+// read reads the secret from its file if it changed since it was read last.
+// Errors only name the file, never its content.
+func (s *Secret) read() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return microerror.Maskf(executionFailedError, "%s file %#q cannot be read", s.name, s.path)
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil
+	}
+	if info.Size() > maxSize {
+		return microerror.Maskf(executionFailedError, "%s file %#q is larger than %d bytes", s.name, s.path, maxSize)
+	}
+
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return microerror.Maskf(executionFailedError, "%s file %#q cannot be read", s.name, s.path)
+	}
+
+	value := strings.TrimSpace(string(b))
+	if value == "" {
+		return microerror.Maskf(executionFailedError, "%s file %#q is empty", s.name, s.path)
+	}
+
+	if s.value != "" {
+		s.logger.Log("level", "info", "message", fmt.Sprintf("%s has been read again from file %#q", s.name, s.path))
+	}
+	s.value = value
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package secret
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/micrologger"
+)
+
+func newTestSecret(t *testing.T, path string) (*Secret, error) {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Config{
+		Logger: logger,
+
+		Name: "GitHub token",
+		Path: path,
+	}
+
+	return New(c)
+}
+
+func Test_Secret_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auto-oncall-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	err = ioutil.WriteFile(path, []byte("first\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := newTestSecret(t, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Value() != "first" {
+		t.Fatalf("expected %#q, got %#q", "first", s.Value())
+	}
+
+	// Files change size in every step, so that they are read again
+	// regardless of the resolution of their modification time.
+	testCases := []struct {
+		name     string
+		update   func() error
+		expected string
+	}{
+		{
+			name: "case 0: file rewritten in place",
+			update: func() error {
+				return ioutil.WriteFile(path, []byte("second\n"), 0600)
+			},
+			expected: "second",
+		},
+		{
+			name: "case 1: file replaced atomically",
+			update: func() error {
+				tmp := filepath.Join(dir, ".token.tmp")
+				err := ioutil.WriteFile(tmp, []byte("  replaced  \n"), 0600)
+				if err != nil {
+					return err
+				}
+				return os.Rename(tmp, path)
+			},
+			expected: "replaced",
+		},
+		{
+			name: "case 2: empty file keeps the previous value",
+			update: func() error {
+				return ioutil.WriteFile(path, []byte("\n"), 0600)
+			},
+			expected: "replaced",
+		},
+		{
+			name: "case 3: missing file keeps the previous value",
+			update: func() error {
+				return os.Remove(path)
+			},
+			expected: "replaced",
+		},
+		{
+			name: "case 4: file written again",
+			update: func() error {
+				return ioutil.WriteFile(path, []byte("third"), 0600)
+			},
+			expected: "third",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.update()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s.Value() != tc.expected {
+				t.Fatalf("expected %#q, got %#q", tc.expected, s.Value())
+			}
+		})
+	}
+}
+
+func Test_Secret_File_Missing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auto-oncall-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	err = ioutil.WriteFile(path, []byte("\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = newTestSecret(t, filepath.Join(dir, "missing"))
+	if !IsExecutionFailed(err) {
+		t.Fatalf("expected execution failed error for a missing file, got %#v", err)
+	}
+	_, err = newTestSecret(t, path)
+	if !IsExecutionFailed(err) {
+		t.Fatalf("expected execution failed error for an empty file, got %#v", err)
+	}
+}
+
+func Test_Secret_String(t *testing.T) {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(Config{Logger: logger, Name: "GitHub token", Value: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Value() != "token" {
+		t.Fatalf("expected %#q, got %#q", "token", s.Value())
+	}
+	for _, f := range []string{"%s", "%v", "%#v", "%+v"} {
+		formatted := fmt.Sprintf(f, struct{ Token *Secret }{s})
+		if strings.Contains(formatted, "token") {
+			t.Fatalf("expected %#q to redact the secret, got %#q", f, formatted)
+		}
+	}
+}
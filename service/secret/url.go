@@ -0,0 +1,44 @@
+package secret
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+type urlKey struct{}
+
+// WithURL returns a context marking the URLs of requests made with it as
+// secret, e.g. of incoming webhooks or private calendar links carrying a
+// token in their path or query.
+func WithURL(ctx context.Context) context.Context {
+	return context.WithValue(ctx, urlKey{}, true)
+}
+
+// IsURL tells whether the URLs of requests made with the given context are
+// secret.
+func IsURL(ctx context.Context) bool {
+	v, _ := ctx.Value(urlKey{}).(bool)
+	return v
+}
+
+// RequestURL returns the URL of the given request as it may be logged or
+// recorded. Secret URLs are reduced to their scheme and host, the password of
+// all others is removed.
+func RequestURL(req *http.Request) string {
+	if IsURL(req.Context()) {
+		return req.URL.Scheme + "://" + req.URL.Host + "/" + redacted
+	}
+
+	return req.URL.Redacted()
+}
+
+// URLError removes the secret URL of the given request from an error returned
+// by an HTTP client, which otherwise contains it.
+func URLError(req *http.Request, err error) error {
+	if e, ok := err.(*url.Error); ok {
+		return &url.Error{Op: e.Op, URL: RequestURL(req), Err: e.Err}
+	}
+
+	return err
+}
@@ -2,24 +2,51 @@
 package service
 
 import (
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/spf13/viper"
 
-	"github.com/giantswarm/opsctl/service/opsgenie"
-
 	"github.com/giantswarm/auto-oncall/flag"
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/audit"
+	"github.com/giantswarm/auto-oncall/service/availability"
+	"github.com/giantswarm/auto-oncall/service/directory"
+	"github.com/giantswarm/auto-oncall/service/dryrun"
+	"github.com/giantswarm/auto-oncall/service/health"
+	"github.com/giantswarm/auto-oncall/service/notifier"
+	"github.com/giantswarm/auto-oncall/service/notifier/outgoing"
+	"github.com/giantswarm/auto-oncall/service/notifier/slack"
+	"github.com/giantswarm/auto-oncall/service/policy"
+	"github.com/giantswarm/auto-oncall/service/provider"
+	"github.com/giantswarm/auto-oncall/service/provider/alertmanager"
+	"github.com/giantswarm/auto-oncall/service/provider/grafana"
+	"github.com/giantswarm/auto-oncall/service/provider/opsgenie"
+	"github.com/giantswarm/auto-oncall/service/secret"
+	"github.com/giantswarm/auto-oncall/service/tracing"
+	"github.com/giantswarm/auto-oncall/service/ttl"
 	"github.com/giantswarm/auto-oncall/service/version"
 	"github.com/giantswarm/auto-oncall/service/webhook"
 )
 
+const (
+	// dryRunLimit is the number of changes recorded in dry-run mode.
+	dryRunLimit = 1000
+)
+
 // Config represents the configuration used to create a new service.
 type Config struct {
-	Logger micrologger.Logger
+	// HttpClient is used for all requests to GitHub and the on-call provider.
+	// It defaults to a client with a timeout of ten seconds.
+	HttpClient *http.Client
+	Logger     micrologger.Logger
 
 	Description string
 	Flag        *flag.Flag
@@ -32,18 +59,44 @@ type Config struct {
 // Service bundles other services.
 type Service struct {
 	// Dependencies
+	Audit *audit.Service
+	// DryRun records the changes the provider would have made. It is nil
+	// unless dry-run mode is enabled.
+	DryRun   *dryrun.Transport
+	Health   *health.Service
+	Notifier *notifier.Service
+	// Tracer starts the traces of webhooks. It is nil unless an exporter is
+	// configured.
+	Tracer  *tracing.Tracer
 	Version *version.Service
 	Webhook *webhook.Service
 
 	// Settings
 	Flag  *flag.Flag
 	Viper *viper.Viper
+
+	bootOnce sync.Once
 }
 
 // New creates a new configured service object.
 func New(config Config) (*Service, error) {
 	var err error
 
+	var auditService *audit.Service
+	{
+		c := audit.Config{
+			Logger: config.Logger,
+
+			DryRun: config.Viper.GetBool(config.Flag.Service.Oncall.DryRun),
+			Path:   config.Viper.GetString(config.Flag.Service.Audit.Path),
+		}
+
+		auditService, err = audit.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var versionService *version.Service
 	{
 		c := version.Config{
@@ -59,35 +112,476 @@ func New(config Config) (*Service, error) {
 		}
 	}
 
-	var opsgenieService *opsgenie.OpsGenie
+	httpClient := config.HttpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: time.Second * 10}
+	}
+
+	// In dry-run mode reads are sent, so that decisions are based on the
+	// actual state, while all changes are only recorded. Assignments are kept
+	// in memory and the Alertmanager configuration is rendered into a
+	// temporary file, so that nothing is left over for a regular run.
+	dryRun := config.Viper.GetBool(config.Flag.Service.Oncall.DryRun)
+	var dryRunTransport *dryrun.Transport
+	if dryRun {
+		c := dryrun.Config{
+			Logger:    config.Logger,
+			Transport: httpClient.Transport,
+
+			Limit:     dryRunLimit,
+			LiveReads: true,
+		}
+
+		dryRunTransport, err = dryrun.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		httpClient = &http.Client{Timeout: httpClient.Timeout, Transport: dryRunTransport}
+	}
+
+	var tracer *tracing.Tracer
 	{
+		var exporter tracing.Exporter
+		switch e := config.Viper.GetString(config.Flag.Service.Tracing.Exporter); e {
+		case "", tracing.ExporterNone:
+		case tracing.ExporterFile:
+			exporter, err = tracing.NewFileExporter(config.Viper.GetString(config.Flag.Service.Tracing.Path))
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		case tracing.ExporterOTLP:
+			c := tracing.OTLPConfig{
+				// Spans are sent with a client of their own, so that exports
+				// are neither traced nor held back in dry-run mode.
+				HttpClient: &http.Client{Timeout: time.Second * 10},
+
+				Endpoint: config.Viper.GetString(config.Flag.Service.Tracing.Endpoint),
+			}
+
+			exporter, err = tracing.NewOTLPExporter(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		case tracing.ExporterStdout:
+			exporter = tracing.NewWriterExporter(os.Stdout)
+		default:
+			return nil, microerror.Maskf(invalidConfigError, "unknown tracing exporter %#q", e)
+		}
+
+		if exporter != nil {
+			c := tracing.Config{
+				Exporter: exporter,
+				Logger:   config.Logger,
+			}
+
+			tracer, err = tracing.New(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			httpClient = &http.Client{Timeout: httpClient.Timeout, Transport: tracing.NewTransport(httpClient.Transport)}
+		}
+	}
+
+	var organizations []webhook.Organization
+	{
+		var configs []webhook.OrganizationConfig
+		err = config.Viper.UnmarshalKey(config.Flag.Service.Oncall.Organizations, &configs)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, c := range configs {
+			if c.Team != "" && config.Viper.GetString(config.Flag.Service.Oncall.Provider) != provider.Opsgenie {
+				return nil, microerror.Maskf(invalidConfigError, "team of organization %#q requires provider %#q", c.Name, provider.Opsgenie)
+			}
+
+			o, err := webhook.NewOrganization(config.Logger, c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			organizations = append(organizations, o)
+		}
+	}
+
+	var webhookSecrets []string
+	{
+		err = config.Viper.UnmarshalKey(config.Flag.Service.Oncall.WebhookSecrets, &webhookSecrets)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var githubToken *secret.Secret
+	{
+		c := secret.Config{
+			Logger: config.Logger,
+
+			Name:  "GitHub token",
+			Path:  config.Viper.GetString(config.Flag.Service.Oncall.GithubTokenFile),
+			Value: config.Viper.GetString(config.Flag.Service.Oncall.GithubToken),
+		}
+
+		githubToken, err = secret.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var opsgenieToken *secret.Secret
+	{
+		c := secret.Config{
+			Logger: config.Logger,
+
+			Name:  "Opsgenie token",
+			Path:  config.Viper.GetString(config.Flag.Service.Oncall.OpsgenieTokenFile),
+			Value: config.Viper.GetString(config.Flag.Service.Oncall.OpsgenieToken),
+		}
+
+		opsgenieToken, err = secret.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var webhookSecret *secret.Secret
+	{
+		c := secret.Config{
+			Logger: config.Logger,
+
+			Name:  "webhook secret",
+			Path:  config.Viper.GetString(config.Flag.Service.Oncall.WebhookSecretFile),
+			Value: config.Viper.GetString(config.Flag.Service.Oncall.WebhookSecret),
+		}
+
+		webhookSecret, err = secret.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var registry *assignment.Registry
+	{
+		path := config.Viper.GetString(config.Flag.Service.State.Path)
+		if path == "" || dryRun {
+			registry = assignment.NewRegistry()
+		} else {
+			registry, err = assignment.NewFileRegistry(path)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+	}
+
+	var oncallProvider provider.Provider
+	switch p := config.Viper.GetString(config.Flag.Service.Oncall.Provider); p {
+	case provider.Alertmanager:
+		output := config.Viper.GetString(config.Flag.Service.Alertmanager.Config.Output)
+		if dryRun {
+			dir, err := ioutil.TempDir("", "auto-oncall-dryrun")
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			output = filepath.Join(dir, "alertmanager.yml")
+		}
+
+		c := alertmanager.Config{
+			HttpClient: httpClient,
+			Logger:     config.Logger,
+
+			BaseConfig:   config.Viper.GetString(config.Flag.Service.Alertmanager.Config.Base),
+			OutputConfig: output,
+			ReloadURL:    config.Viper.GetString(config.Flag.Service.Alertmanager.ReloadURL),
+		}
+
+		oncallProvider, err = alertmanager.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case provider.Grafana:
+		c := grafana.Config{
+			HttpClient: httpClient,
+			Logger:     config.Logger,
+
+			Integration: config.Viper.GetString(config.Flag.Service.Grafana.Integration),
+			Mode:        config.Viper.GetString(config.Flag.Service.Grafana.Mode),
+			Schedule:    config.Viper.GetString(config.Flag.Service.Grafana.Schedule),
+			Team:        config.Viper.GetString(config.Flag.Service.Grafana.Team),
+			Token:       config.Viper.GetString(config.Flag.Service.Grafana.Token),
+			URL:         config.Viper.GetString(config.Flag.Service.Grafana.URL),
+		}
+
+		oncallProvider, err = grafana.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case provider.Opsgenie:
+		var teams []string
+		for _, o := range organizations {
+			if o.Team != "" {
+				teams = append(teams, o.Team)
+			}
+		}
+
+		var policies []opsgenie.Policy
+		err = config.Viper.UnmarshalKey(config.Flag.Service.Opsgenie.Escalation.Policies, &policies)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		var policySelectors []opsgenie.PolicySelector
+		err = config.Viper.UnmarshalKey(config.Flag.Service.Opsgenie.Escalation.Selectors, &policySelectors)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		var conditions []opsgenie.RoutingRuleCondition
+		err = config.Viper.UnmarshalKey(config.Flag.Service.Opsgenie.RoutingRule.Conditions, &conditions)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
 		c := opsgenie.Config{
-			Logger:    config.Logger,
-			AuthToken: config.Viper.GetString(config.Flag.Service.Oncall.OpsgenieToken),
+			HttpClient: httpClient,
+			Logger:     config.Logger,
+
+			Conditions:      conditions,
+			Mode:            config.Viper.GetString(config.Flag.Service.Opsgenie.Mode),
+			Order:           config.Viper.GetInt(config.Flag.Service.Opsgenie.RoutingRule.Order),
+			Policies:        policies,
+			PolicySelectors: policySelectors,
+			Registry:        registry,
+			Schedule:        config.Viper.GetString(config.Flag.Service.Opsgenie.Schedule),
+			Team:            config.Viper.GetString(config.Flag.Service.Opsgenie.Team),
+			Teams:           teams,
+			Token:           opsgenieToken,
 		}
-		opsgenieService, err = opsgenie.New(c)
+
+		oncallProvider, err = opsgenie.New(c)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "unknown provider %#q", p)
 	}
 
-	var webhookService *webhook.Service
+	users := make(map[string]string)
+	// allUsers are the user mappings of the service and all organizations,
+	// backups are looked up in.
+	allUsers := make(map[string]string)
 	{
-		users := make(map[string]string)
 		userList := config.Viper.GetString(config.Flag.Service.Oncall.Users)
 		for _, user := range strings.Split(userList, ",") {
+			// The user mapping may be left empty when all organizations
+			// have their own.
+			if user == "" {
+				continue
+			}
 			kv := strings.Split(user, ":")
+			if len(kv) != 2 {
+				return nil, microerror.Maskf(invalidConfigError, "user mapping %#q must be given as github_id:user", user)
+			}
 			users[kv[0]] = kv[1]
+			allUsers[kv[0]] = kv[1]
+		}
+		for _, o := range organizations {
+			for githubLogin, user := range o.Users {
+				if _, ok := allUsers[githubLogin]; !ok {
+					allUsers[githubLogin] = user
+				}
+			}
+		}
+	}
+
+	var userDirectory *directory.Directory
+	{
+		var directoryUsers []directory.User
+		err = config.Viper.UnmarshalKey(config.Flag.Service.Directory.Users, &directoryUsers)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		var teams []directory.Team
+		err = config.Viper.UnmarshalKey(config.Flag.Service.Directory.Teams, &teams)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		userDirectory, err = directory.New(directoryUsers, teams)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var availabilitySources []availability.Source
+	{
+		var calendars bool
+		for _, u := range userDirectory.Users() {
+			if t, _ := userDirectory.Team(u.Github); u.Calendar != "" || t.Calendar != "" {
+				calendars = true
+			}
+		}
+		if calendars {
+			c := availability.CalendarConfig{
+				HttpClient: httpClient,
+				Logger:     config.Logger,
+
+				RefreshInterval: config.Viper.GetDuration(config.Flag.Service.Policy.Availability.RefreshInterval),
+			}
+
+			calendar, err := availability.NewCalendar(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			availabilitySources = append(availabilitySources, calendar)
+		}
+
+		if config.Viper.GetBool(config.Flag.Service.Policy.Availability.Opsgenie) {
+			s, ok := oncallProvider.(availability.Source)
+			if !ok {
+				return nil, microerror.Maskf(invalidConfigError, "availability in Opsgenie requires provider %#q", provider.Opsgenie)
+			}
+			availabilitySources = append(availabilitySources, s)
+		}
+	}
+
+	var oncallPolicy *policy.Policy
+	{
+		c := policy.Config{
+			Directory: userDirectory,
+			Logger:    config.Logger,
+
+			OutsideWorkingHours: config.Viper.GetString(config.Flag.Service.Policy.OutsideWorkingHours),
+			Schedule:            config.Viper.GetString(config.Flag.Service.Policy.Schedule),
+			Sources:             availabilitySources,
+			Users:               allUsers,
+		}
+
+		// Override shifts take the place of the regular on-call, who
+		// therefore can neither be paged together with the deployer nor be
+		// put on call instead of them.
+		var overrides bool
+		switch config.Viper.GetString(config.Flag.Service.Oncall.Provider) {
+		case provider.Grafana:
+			overrides = config.Viper.GetString(config.Flag.Service.Grafana.Mode) == grafana.ModeOverride
+		case provider.Opsgenie:
+			overrides = config.Viper.GetString(config.Flag.Service.Opsgenie.Mode) == opsgenie.ModeOverride
+		}
+		if overrides && c.OutsideWorkingHours == policy.ActionCoresponder {
+			return nil, microerror.Maskf(invalidConfigError, "policy %#q cannot be used with override mode", policy.ActionCoresponder)
+		}
+		c.Schedules = !overrides
+
+		oncallPolicy, err = policy.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var notifierService *notifier.Service
+	{
+		var notifiers []notifier.Notifier
+
+		token := config.Viper.GetString(config.Flag.Service.Slack.Token)
+		webhookURL := config.Viper.GetString(config.Flag.Service.Slack.WebhookURL)
+		if token != "" || webhookURL != "" {
+			var templates slack.Templates
+			err = config.Viper.UnmarshalKey(config.Flag.Service.Slack.Templates, &templates)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			c := slack.Config{
+				Directory:  userDirectory,
+				HttpClient: httpClient,
+				Logger:     config.Logger,
+
+				APIURL:     config.Viper.GetString(config.Flag.Service.Slack.APIURL),
+				Channel:    config.Viper.GetString(config.Flag.Service.Slack.Channel),
+				Templates:  templates,
+				Token:      token,
+				WebhookURL: webhookURL,
+			}
+
+			n, err := slack.New(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			notifiers = append(notifiers, n)
+		}
+
+		var endpoints []outgoing.Endpoint
+		err = config.Viper.UnmarshalKey(config.Flag.Service.Notifier.Webhooks, &endpoints)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		for _, e := range endpoints {
+			c := outgoing.Config{
+				HttpClient: httpClient,
+				Logger:     config.Logger,
+
+				Endpoint: e,
+			}
+
+			n, err := outgoing.New(c)
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			notifiers = append(notifiers, n)
+		}
+
+		c := notifier.Config{
+			Logger: config.Logger,
+
+			Notifiers: notifiers,
+		}
+
+		notifierService, err = notifier.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var ttlRules *ttl.Rules
+	{
+		var rules []ttl.Rule
+		err = config.Viper.UnmarshalKey(config.Flag.Service.TTL.Rules, &rules)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		c := ttl.Config{
+			Default: config.Viper.GetDuration(config.Flag.Service.TTL.Default),
+			Rules:   rules,
 		}
 
+		ttlRules, err = ttl.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var webhookService *webhook.Service
+	{
 		webhookConfig := webhook.Config{
-			HttpClient: &http.Client{Timeout: time.Second * 10},
+			Audit:      auditService,
+			HttpClient: httpClient,
 			Logger:     config.Logger,
 
-			GithubToken:   config.Viper.GetString(config.Flag.Service.Oncall.GithubToken),
-			Opsgenie:      opsgenieService,
-			Users:         users,
-			WebhookSecret: config.Viper.GetString(config.Flag.Service.Oncall.WebhookSecret),
+			AnnounceMode:      config.Viper.GetString(config.Flag.Service.Github.Announce.Mode),
+			AnnounceTemplate:  config.Viper.GetString(config.Flag.Service.Github.Announce.Template),
+			ExternalURL:       config.Viper.GetString(config.Flag.Service.Oncall.ExternalURL),
+			GithubToken:       githubToken,
+			Handover:          config.Viper.GetString(config.Flag.Service.Oncall.Handover),
+			Notifier:          notifierService,
+			Organizations:     organizations,
+			Policy:            oncallPolicy,
+			Provider:          oncallProvider,
+			ReconcileInterval: config.Viper.GetDuration(config.Flag.Service.State.ReconcileInterval),
+			Registry:          registry,
+			TTL:               ttlRules,
+			Users:             users,
+			WebhookSecret:     webhookSecret,
+			WebhookSecrets:    webhookSecrets,
 		}
 
 		webhookService, err = webhook.New(webhookConfig)
@@ -96,10 +590,51 @@ func New(config Config) (*Service, error) {
 		}
 	}
 
+	var healthService *health.Service
+	{
+		readiness := map[string]health.Checker{
+			"github": health.CheckerFunc(webhookService.CheckGithub),
+			"queue":  health.CheckerFunc(webhookService.CheckQueue),
+			"state":  registry,
+		}
+		if c, ok := oncallProvider.(provider.Checker); ok {
+			readiness[config.Viper.GetString(config.Flag.Service.Oncall.Provider)] = c
+		}
+
+		c := health.Config{
+			Logger: config.Logger,
+
+			CacheTTL: config.Viper.GetDuration(config.Flag.Service.Health.CacheTTL),
+			Liveness: map[string]health.Checker{
+				"state": registry,
+			},
+			Readiness: readiness,
+		}
+
+		healthService, err = health.New(c)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	newService := &Service{
-		Version: versionService,
-		Webhook: webhookService,
+		Audit:    auditService,
+		DryRun:   dryRunTransport,
+		Health:   healthService,
+		Notifier: notifierService,
+		Tracer:   tracer,
+		Version:  versionService,
+		Webhook:  webhookService,
 	}
 
 	return newService, nil
 }
+
+// Boot starts the background work of the services.
+func (s *Service) Boot() {
+	s.bootOnce.Do(func() {
+		go s.Notifier.Boot()
+		go s.Tracer.Boot()
+		go s.Webhook.Boot()
+	})
+}
@@ -0,0 +1,247 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// ExporterFile writes spans as JSON lines to a file.
+	ExporterFile = "file"
+	// ExporterNone disables tracing.
+	ExporterNone = "none"
+	// ExporterOTLP sends spans to an OpenTelemetry collector via OTLP/HTTP.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans as JSON lines to stdout.
+	ExporterStdout = "stdout"
+
+	otlpTracesPath = "/v1/traces"
+
+	// serviceName is the service name spans are reported with.
+	serviceName = "auto-oncall"
+)
+
+// OTLP span kinds and status codes, see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto.
+var otlpKinds = map[string]int{
+	KindInternal: 1,
+	KindServer:   2,
+	KindClient:   3,
+}
+
+const (
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+// Exporter sends finished spans to a tracing backend.
+type Exporter interface {
+	Export(records []Record) error
+}
+
+// WriterExporter writes spans as JSON lines.
+type WriterExporter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// NewWriterExporter creates an exporter writing to the given writer.
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{
+		writer: w,
+	}
+}
+
+// NewFileExporter creates an exporter appending to the file at the given
+// path.
+func NewFileExporter(path string) (*WriterExporter, error) {
+	if path == "" {
+		return nil, microerror.Maskf(invalidConfigError, "path must not be empty")
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return NewWriterExporter(f), nil
+}
+
+func (e *WriterExporter) Export(records []Record) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		err := enc.Encode(r)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	_, err := e.writer.Write(buf.Bytes())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// OTLPConfig represents the configuration used to create an OTLP exporter.
+type OTLPConfig struct {
+	// HttpClient sends spans to the collector. It must not be traced itself.
+	HttpClient *http.Client
+
+	// Endpoint is the base URL of the OTLP/HTTP receiver, e.g.
+	// http://localhost:4318. Spans are sent to its /v1/traces path.
+	Endpoint string
+}
+
+// OTLPExporter sends spans to an OpenTelemetry collector using the JSON
+// encoding of OTLP/HTTP.
+type OTLPExporter struct {
+	httpClient *http.Client
+
+	url string
+}
+
+// NewOTLPExporter creates a new configured OTLP exporter.
+func NewOTLPExporter(config OTLPConfig) (*OTLPExporter, error) {
+	if config.HttpClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.HttpClient must not be empty", config)
+	}
+	if config.Endpoint == "" {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Endpoint must not be empty", config)
+	}
+
+	e := &OTLPExporter{
+		httpClient: config.HttpClient,
+
+		url: strings.TrimSuffix(config.Endpoint, "/") + otlpTracesPath,
+	}
+
+	return e, nil
+}
+
+func (e *OTLPExporter) Export(records []Record) error {
+	spans := make([]otlpSpan, 0, len(records))
+	for _, r := range records {
+		s := otlpSpan{
+			TraceID:           r.TraceID,
+			SpanID:            r.SpanID,
+			ParentSpanID:      r.ParentID,
+			Name:              r.Name,
+			Kind:              otlpKinds[r.Kind],
+			StartTimeUnixNano: strconv.FormatInt(r.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(r.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(r.Attributes),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		if r.Error != "" {
+			s.Status = otlpStatus{Code: otlpStatusError, Message: r.Error}
+		}
+
+		spans = append(spans, s)
+	}
+
+	request := otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: otlpAttributes(map[string]string{"service.name": serviceName}),
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: serviceName},
+						Spans: spans,
+					},
+				},
+			},
+		},
+	}
+
+	b, err := json.Marshal(request)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	resp, err := e.httpClient.Post(e.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return microerror.Maskf(exportFailedError, "POST %s: expected 2xx, got %d: %s", e.url, resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func otlpAttributes(attributes map[string]string) []otlpAttribute {
+	var list []otlpAttribute
+	for k, v := range attributes {
+		list = append(list, otlpAttribute{Key: k, Value: otlpValue{StringValue: v}})
+	}
+
+	return list
+}
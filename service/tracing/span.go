@@ -0,0 +1,162 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// KindClient is the kind of spans of outgoing requests.
+	KindClient = "client"
+	// KindInternal is the kind of spans of processing stages.
+	KindInternal = "internal"
+	// KindServer is the kind of spans of incoming requests.
+	KindServer = "server"
+)
+
+type contextKey struct{}
+
+// Record is a finished span as handed to exporters.
+type Record struct {
+	TraceID  string    `json:"traceId"`
+	SpanID   string    `json:"spanId"`
+	ParentID string    `json:"parentSpanId,omitempty"`
+	Name     string    `json:"name"`
+	Kind     string    `json:"kind"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	// Attributes describe what the span covers, e.g. the delivery ID.
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Error is the reason the span failed, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+// Span is a stage of processing a webhook. A nil span is valid and does
+// nothing, so that code does not need to care whether tracing is enabled.
+// Spans must not be used concurrently.
+type Span struct {
+	tracer *Tracer
+	record Record
+}
+
+// Start starts a span with the given name as child of the span in the given
+// context. It returns a context carrying the new span. Without a span in the
+// given context nothing is traced and the returned span is nil.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal)
+}
+
+// StartKind is like Start, with the kind of the span given.
+func StartKind(ctx context.Context, name, kind string) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+
+	s := &Span{
+		tracer: parent.tracer,
+		record: Record{
+			TraceID:  parent.record.TraceID,
+			SpanID:   newSpanID(),
+			ParentID: parent.record.SpanID,
+			Name:     name,
+			Kind:     kind,
+			Start:    time.Now(),
+		},
+	}
+
+	return context.WithValue(ctx, contextKey{}, s), s
+}
+
+// FromContext returns the span carried by the given context, if any.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(contextKey{}).(*Span)
+	return s
+}
+
+// Detach returns a context carrying the span of the given context, but
+// neither its deadline nor its cancellation. It is used to continue a trace
+// after the request it started with is done.
+func Detach(ctx context.Context) context.Context {
+	s := FromContext(ctx)
+	if s == nil {
+		return context.Background()
+	}
+
+	return context.WithValue(context.Background(), contextKey{}, s)
+}
+
+// SetAttribute sets the attribute with the given key to the given value.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	if s.record.Attributes == nil {
+		s.record.Attributes = map[string]string{}
+	}
+
+	s.record.Attributes[key] = fmt.Sprint(value)
+}
+
+// TraceID returns the ID of the trace the span belongs to.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+
+	return s.record.TraceID
+}
+
+// End finishes the span and hands it to the exporter. The span is marked as
+// failed if an error is given.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	if err != nil {
+		s.record.Error = err.Error()
+	}
+	s.record.End = time.Now()
+
+	s.tracer.add(s.record)
+}
+
+// traceID derives the trace ID from the given key, so that all spans of a
+// webhook delivery end up in the same trace and can be found by its ID.
+// GitHub delivery IDs are UUIDs, which are used as they are. Other keys are
+// hashed. Without a key a random ID is used.
+func traceID(key string) string {
+	if key == "" {
+		return randomID(16)
+	}
+
+	id := strings.ToLower(strings.Replace(key, "-", "", -1))
+	if _, err := hex.DecodeString(id); err == nil && len(id) == 32 {
+		return id
+	}
+
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:16])
+}
+
+func newSpanID() string {
+	return randomID(8)
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		// crypto/rand does not fail on supported platforms. A time based ID
+		// is good enough to not lose the span if it does.
+		return fmt.Sprintf("%0*x", n*2, time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,119 @@
+// Package tracing records the stages of processing webhooks as spans, grouped
+// into one trace per webhook delivery, and exports them via OTLP or as JSON
+// lines for local use.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	// batchSize is the number of spans exported at once at most.
+	batchSize = 100
+	// flushInterval is the interval spans are exported in, unless a batch is
+	// full before.
+	flushInterval = 5 * time.Second
+	// queueSize is the number of finished spans waiting to be exported at
+	// most. Further spans are dropped.
+	queueSize = 2048
+)
+
+// Config represents the configuration used to create a tracer.
+type Config struct {
+	// Dependencies.
+	Exporter Exporter
+	Logger   micrologger.Logger
+}
+
+// Tracer starts traces and exports their spans in batches. A nil tracer is
+// valid and traces nothing.
+type Tracer struct {
+	exporter Exporter
+	logger   micrologger.Logger
+
+	queue chan Record
+}
+
+// New creates a new configured tracer.
+func New(config Config) (*Tracer, error) {
+	if config.Exporter == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Exporter must not be empty", config)
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Logger must not be empty", config)
+	}
+
+	t := &Tracer{
+		exporter: config.Exporter,
+		logger:   config.Logger,
+
+		queue: make(chan Record, queueSize),
+	}
+
+	return t, nil
+}
+
+// StartTrace starts the root span of the trace identified by the given key,
+// usually the ID of a webhook delivery. It returns a context carrying the
+// span, which child spans are started from with Start.
+func (t *Tracer) StartTrace(ctx context.Context, name, key string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	s := &Span{
+		tracer: t,
+		record: Record{
+			TraceID: traceID(key),
+			SpanID:  newSpanID(),
+			Name:    name,
+			Kind:    KindServer,
+			Start:   time.Now(),
+		},
+	}
+
+	return context.WithValue(ctx, contextKey{}, s), s
+}
+
+// Boot exports finished spans. It blocks forever.
+func (t *Tracer) Boot() {
+	if t == nil {
+		return
+	}
+
+	var batch []Record
+	flush := time.Tick(flushInterval)
+
+	for {
+		select {
+		case r := <-t.queue:
+			batch = append(batch, r)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-flush:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		err := t.exporter.Export(batch)
+		if err != nil {
+			t.logger.Log("level", "error", "message", fmt.Sprintf("exporting %d spans failed", len(batch)), "stack", fmt.Sprintf("%#v", err))
+		}
+		batch = nil
+	}
+}
+
+func (t *Tracer) add(r Record) {
+	select {
+	case t.queue <- r:
+	default:
+		t.logger.Log("level", "warning", "message", "dropping span, export queue is full", "span", r.Name, "trace", r.TraceID)
+	}
+}
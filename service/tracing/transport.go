@@ -0,0 +1,48 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/giantswarm/auto-oncall/service/secret"
+)
+
+// Transport is an http.RoundTripper recording a client span for each request
+// made with a traced context, e.g. lookups at GitHub and calls to the on-call
+// backend.
+type Transport struct {
+	transport http.RoundTripper
+}
+
+// NewTransport creates a transport sending requests with the given transport.
+// It defaults to http.DefaultTransport.
+func NewTransport(transport http.RoundTripper) *Transport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Transport{
+		transport: transport,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	_, span := StartKind(req.Context(), fmt.Sprintf("%s %s", req.Method, req.URL.Host), KindClient)
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", secret.RequestURL(req))
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		span.End(err)
+		return nil, err
+	}
+
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.End(fmt.Errorf("%s %s: %s", req.Method, req.URL.Path, resp.Status))
+	} else {
+		span.End(nil)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,194 @@
+// Package ttl decides how long deployers stay on call for a deployment, based
+// on rules per repository and environment and on the size of the change.
+package ttl
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	// DefaultTTL is the TTL of assignments without matching rule by default.
+	DefaultTTL = time.Hour
+	// DefaultSupersededMax is the time assignments lasting until superseded
+	// end after at the latest by default.
+	DefaultSupersededMax = 7 * 24 * time.Hour
+
+	defaultDescription = "by default"
+)
+
+// Config represents the configuration used to create rules.
+type Config struct {
+	// Default is the TTL of assignments without matching rule. It defaults
+	// to DefaultTTL.
+	Default time.Duration
+	// Rules select the TTL by repository and environment. The first matching
+	// rule wins.
+	Rules []Rule
+}
+
+// Rule sets the TTL of assignments whose repository and environment match the
+// given shell patterns. Empty patterns match everything.
+type Rule struct {
+	Environment string
+	Repository  string
+
+	// TTL is the TTL of assignments. It defaults to the default TTL.
+	TTL time.Duration
+	// PerCommit and PerLine are added to the TTL for every commit and every
+	// line changed since the previous deployment to the environment.
+	PerCommit time.Duration
+	PerLine   time.Duration
+	// Max caps the TTL, including the time added for the change.
+	Max time.Duration
+	// UntilSuperseded keeps the deployer on call until the repository is
+	// deployed to the environment again, at most for Max, which defaults to
+	// DefaultSupersededMax.
+	UntilSuperseded bool
+
+	// description names the rule in decisions.
+	description string
+}
+
+// Change is the size of a deployed change.
+type Change struct {
+	Commits int
+	Lines   int
+}
+
+// Decision is the TTL decided for an assignment.
+type Decision struct {
+	// TTL is the TTL of the assignment, e.g. 1h30m0s.
+	TTL string `json:"ttl"`
+	// UntilSuperseded tells whether the assignment ends when the repository
+	// is deployed to the environment again.
+	UntilSuperseded bool `json:"untilSuperseded,omitempty"`
+	// Reason explains the decision.
+	Reason string `json:"reason"`
+}
+
+// Rules select the TTL of assignments.
+type Rules struct {
+	fallback Rule
+	rules    []Rule
+}
+
+// New creates new configured rules.
+func New(config Config) (*Rules, error) {
+	if config.Default == 0 {
+		config.Default = DefaultTTL
+	}
+	if config.Default < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "%T.Default must not be negative", config)
+	}
+
+	var rules []Rule
+	for i, r := range config.Rules {
+		for _, p := range []string{r.Environment, r.Repository} {
+			_, err := path.Match(p, "")
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "TTL rule %d has invalid pattern %#q", i, p)
+			}
+		}
+		if r.TTL < 0 || r.PerCommit < 0 || r.PerLine < 0 || r.Max < 0 {
+			return nil, microerror.Maskf(invalidConfigError, "TTL rule %d has negative durations", i)
+		}
+
+		r.description = fmt.Sprintf("for repository %#q in environment %#q", pattern(r.Repository), pattern(r.Environment))
+		if r.TTL == 0 {
+			r.TTL = config.Default
+		}
+		if r.UntilSuperseded && r.Max == 0 {
+			r.Max = DefaultSupersededMax
+		}
+		if r.Max != 0 && !r.UntilSuperseded && r.Max < r.TTL {
+			return nil, microerror.Maskf(invalidConfigError, "TTL rule %d has max %s below TTL %s", i, r.Max, r.TTL)
+		}
+
+		rules = append(rules, r)
+	}
+
+	t := &Rules{
+		fallback: Rule{TTL: config.Default, description: defaultDescription},
+		rules:    rules,
+	}
+
+	return t, nil
+}
+
+// Match returns the first rule matching the given repository and environment,
+// or a rule with the default TTL.
+func (t *Rules) Match(repository, environment string) Rule {
+	for _, r := range t.rules {
+		if match(r.Repository, repository) && match(r.Environment, environment) {
+			return r
+		}
+	}
+
+	return t.fallback
+}
+
+// Scaled tells whether the TTL depends on the size of the change.
+func (r Rule) Scaled() bool {
+	return !r.UntilSuperseded && (r.PerCommit > 0 || r.PerLine > 0)
+}
+
+// Decide returns the TTL of an assignment for the given change, which is nil
+// when the size of the change is unknown or not needed.
+func (r Rule) Decide(c *Change) (time.Duration, Decision) {
+	if r.UntilSuperseded {
+		return r.Max, Decision{
+			TTL:             r.Max.String(),
+			UntilSuperseded: true,
+			Reason:          fmt.Sprintf("until the next deployment %s, at most %s", r.description, r.Max),
+		}
+	}
+
+	ttl := r.TTL
+	reasons := []string{fmt.Sprintf("%s %s", r.TTL, r.description)}
+	if r.Scaled() && c == nil {
+		reasons = append(reasons, "nothing for the change of unknown size")
+	}
+	if r.Scaled() && c != nil {
+		if r.PerCommit > 0 {
+			ttl += time.Duration(c.Commits) * r.PerCommit
+			reasons = append(reasons, fmt.Sprintf("%s for %d commits", time.Duration(c.Commits)*r.PerCommit, c.Commits))
+		}
+		if r.PerLine > 0 {
+			ttl += time.Duration(c.Lines) * r.PerLine
+			reasons = append(reasons, fmt.Sprintf("%s for %d lines changed", time.Duration(c.Lines)*r.PerLine, c.Lines))
+		}
+	}
+	reason := strings.Join(reasons, " plus ")
+	if r.Max > 0 && ttl > r.Max {
+		ttl = r.Max
+		reason = fmt.Sprintf("%s, capped at %s", reason, r.Max)
+	}
+
+	return ttl, Decision{
+		TTL:    ttl.String(),
+		Reason: reason,
+	}
+}
+
+// pattern returns the given pattern, * when it is empty.
+func pattern(p string) string {
+	if p == "" {
+		return "*"
+	}
+
+	return p
+}
+
+func match(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	ok, _ := path.Match(pattern, s)
+	return ok
+}
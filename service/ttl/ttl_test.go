@@ -0,0 +1,154 @@
+package ttl
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Rules_Decide(t *testing.T) {
+	rules, err := New(Config{
+		Default: 30 * time.Minute,
+		Rules: []Rule{
+			{
+				Repository:      "*-operator",
+				Environment:     "production-*",
+				UntilSuperseded: true,
+			},
+			{
+				Repository: "*-operator",
+				TTL:        time.Hour,
+				PerCommit:  10 * time.Minute,
+				PerLine:    time.Second,
+				Max:        3 * time.Hour,
+			},
+			{
+				Environment: "staging",
+				TTL:         2 * time.Hour,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name             string
+		repository       string
+		environment      string
+		change           *Change
+		expectedTTL      time.Duration
+		expectedDecision Decision
+	}{
+		{
+			name:        "case 0: default without matching rule",
+			repository:  "api",
+			environment: "anteater",
+			expectedTTL: 30 * time.Minute,
+			expectedDecision: Decision{
+				TTL:    "30m0s",
+				Reason: "30m0s by default",
+			},
+		},
+		{
+			name:        "case 1: first matching rule wins",
+			repository:  "aws-operator",
+			environment: "production-eu",
+			change:      &Change{Commits: 3, Lines: 100},
+			expectedTTL: DefaultSupersededMax,
+			expectedDecision: Decision{
+				TTL:             "168h0m0s",
+				UntilSuperseded: true,
+				Reason:          "until the next deployment for repository `*-operator` in environment `production-*`, at most 168h0m0s",
+			},
+		},
+		{
+			name:        "case 2: scaled by the size of the change",
+			repository:  "aws-operator",
+			environment: "anteater",
+			change:      &Change{Commits: 3, Lines: 120},
+			expectedTTL: time.Hour + 30*time.Minute + 2*time.Minute,
+			expectedDecision: Decision{
+				TTL:    "1h32m0s",
+				Reason: "1h0m0s for repository `*-operator` in environment `*` plus 30m0s for 3 commits plus 2m0s for 120 lines changed",
+			},
+		},
+		{
+			name:        "case 3: change of unknown size",
+			repository:  "aws-operator",
+			environment: "anteater",
+			expectedTTL: time.Hour,
+			expectedDecision: Decision{
+				TTL:    "1h0m0s",
+				Reason: "1h0m0s for repository `*-operator` in environment `*` plus nothing for the change of unknown size",
+			},
+		},
+		{
+			name:        "case 4: capped at max",
+			repository:  "aws-operator",
+			environment: "anteater",
+			change:      &Change{Commits: 20},
+			expectedTTL: 3 * time.Hour,
+			expectedDecision: Decision{
+				TTL:    "3h0m0s",
+				Reason: "1h0m0s for repository `*-operator` in environment `*` plus 3h20m0s for 20 commits plus 0s for 0 lines changed, capped at 3h0m0s",
+			},
+		},
+		{
+			name:        "case 5: unscaled rule ignores the change",
+			repository:  "api",
+			environment: "staging",
+			change:      &Change{Commits: 20},
+			expectedTTL: 2 * time.Hour,
+			expectedDecision: Decision{
+				TTL:    "2h0m0s",
+				Reason: "2h0m0s for repository `*` in environment `staging`",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ttl, decision := rules.Match(tc.repository, tc.environment).Decide(tc.change)
+			if ttl != tc.expectedTTL {
+				t.Fatalf("expected TTL %s, got %s", tc.expectedTTL, ttl)
+			}
+			if !reflect.DeepEqual(decision, tc.expectedDecision) {
+				t.Fatalf("expected decision %#v, got %#v", tc.expectedDecision, decision)
+			}
+		})
+	}
+}
+
+func Test_New_Invalid(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config Config
+	}{
+		{
+			name:   "case 0: negative default",
+			config: Config{Default: -time.Minute},
+		},
+		{
+			name:   "case 1: invalid pattern",
+			config: Config{Rules: []Rule{{Repository: "["}}},
+		},
+		{
+			name:   "case 2: negative duration",
+			config: Config{Rules: []Rule{{PerLine: -time.Second}}},
+		},
+		{
+			name:   "case 3: max below TTL",
+			config: Config{Rules: []Rule{{TTL: 2 * time.Hour, Max: time.Hour}}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.config)
+			if !IsInvalidConfig(err) {
+				t.Fatalf("expected invalid config error, got %#v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/audit"
+	"github.com/giantswarm/auto-oncall/service/notifier"
+)
+
+const (
+	// manualRef is the ref of assignments created manually.
+	manualRef = "manual"
+	// resolutionManual is the resolution of assignments created manually.
+	resolutionManual = "manual"
+)
+
+// Filter selects assignments. Empty fields match all assignments.
+type Filter struct {
+	Environment  string
+	Organization string
+	Repository   string
+	User         string
+}
+
+// Assignments returns the active assignments matching the given filter,
+// ordered by expiry, followed by the matching pending assignments, ordered by
+// start.
+func (s *Service) Assignments(f Filter) []assignment.Assignment {
+	var assignments []assignment.Assignment
+	for _, a := range append(s.registry.All(), s.registry.Pending()...) {
+		if f.Environment != "" && a.Environment != f.Environment {
+			continue
+		}
+		if f.Organization != "" && !strings.EqualFold(a.Organization, f.Organization) {
+			continue
+		}
+		if f.Repository != "" && a.Repository != f.Repository {
+			continue
+		}
+		if f.User != "" && a.User != f.User && a.GithubLogin != f.User {
+			continue
+		}
+
+		assignments = append(assignments, a)
+	}
+
+	return assignments
+}
+
+// Assign puts the user mapped to the given GitHub login on call for the
+// repository and environment for the given duration, as if they had deployed
+// it. The repository belongs to the given organization, if configured, to
+// other organizations otherwise. Active assignments are handed over according
+// to the handover mode. The given actor is recorded in the audit log, as for
+// all admin operations.
+func (s *Service) Assign(ctx context.Context, actorName, organization, repository, environment, githubLogin string, ttl time.Duration) (assignment.Assignment, error) {
+	if repository == "" || environment == "" || githubLogin == "" {
+		return assignment.Assignment{}, microerror.Maskf(invalidRequestError, "repository, environment and user must not be empty")
+	}
+	if ttl <= 0 {
+		return assignment.Assignment{}, microerror.Maskf(invalidRequestError, "ttl must be positive")
+	}
+
+	o, ok := s.organizations[strings.ToLower(organization)]
+	if organization != "" && !ok {
+		return assignment.Assignment{}, microerror.Maskf(invalidRequestError, "organization %#q not configured", organization)
+	} else if !ok {
+		o = Organization{Users: s.users}
+	}
+
+	user, ok := o.Users[githubLogin]
+	if !ok {
+		return assignment.Assignment{}, microerror.Maskf(userNotFoundError, "%#q", githubLogin)
+	}
+
+	a := assignment.New(repository, manualRef, environment, githubLogin, user, time.Now().Add(ttl))
+	a.Organization = o.Name
+	a.Team = o.Team
+	a.Resolution = resolutionManual
+
+	a, err := s.assign(ctx, a, admin(actorName))
+	if err != nil {
+		return assignment.Assignment{}, microerror.Mask(err)
+	}
+
+	s.logger.Log("level", "info", "message", "assigned manually", "assignment", a.Name, "user", a.User)
+
+	return a, nil
+}
+
+// Extend moves the expiry of the active or pending assignment with the given
+// name to the given duration from now.
+func (s *Service) Extend(ctx context.Context, actorName, name string, ttl time.Duration) (assignment.Assignment, error) {
+	if ttl <= 0 {
+		return assignment.Assignment{}, microerror.Maskf(invalidRequestError, "ttl must be positive")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	a, ok := s.registry.Get(name)
+	if !ok {
+		return assignment.Assignment{}, microerror.Maskf(notFoundError, "assignment %#q", name)
+	}
+
+	a.Expiry = time.Now().Add(ttl).UTC()
+	err := s.extend(ctx, a, admin(actorName), "")
+	if err != nil {
+		return assignment.Assignment{}, microerror.Mask(err)
+	}
+
+	s.logger.Log("level", "info", "message", "extended assignment manually", "assignment", a.Name, "user", a.User, "until", a.Expiry.Format(time.RFC3339))
+
+	return a, nil
+}
+
+// Revoke deletes the active or pending assignment with the given name.
+func (s *Service) Revoke(ctx context.Context, actorName, name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	a, ok := s.registry.Get(name)
+	if !ok {
+		return microerror.Maskf(notFoundError, "assignment %#q", name)
+	}
+
+	err := s.delete(ctx, a, admin(actorName), "revoked")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	s.logger.Log("level", "info", "message", "revoked assignment", "assignment", a.Name, "user", a.User)
+	s.notify(ctx, notifier.EventRevoked, a, nil, admin(actorName))
+
+	return nil
+}
+
+// Cleanup deletes expired assignments right away instead of waiting for the
+// next scheduled cleanup. It returns the number of deleted assignments.
+func (s *Service) Cleanup(ctx context.Context, actorName string) int {
+	return s.reap(ctx, admin(actorName))
+}
+
+func admin(name string) actor {
+	return actor{name: name, origin: audit.OriginAdmin}
+}
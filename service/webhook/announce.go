@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/tracing"
+)
+
+const (
+	// AnnounceComment announces assignments as comment on the deployed
+	// commit, which shows up in pull requests containing it.
+	AnnounceComment = "comment"
+	// AnnounceNone does not announce assignments.
+	AnnounceNone = "none"
+	// AnnounceStatus announces assignments in the description of a new
+	// deployment status, keeping the state of the latest one.
+	AnnounceStatus = "status"
+
+	// DefaultAnnounceTemplate is the template of announcements used when none
+	// is configured.
+	DefaultAnnounceTemplate = `@{{ .GithubLogin }} is on call for {{ .Repository }} on {{ .Environment }}{{ if .Start }} from {{ .Start.Format "2006-01-02 15:04 MST" }}{{ end }} until {{ .Expiry.Format "2006-01-02 15:04 MST" }}.{{ if .RevokeURL }} Revoke: {{ .RevokeURL }}{{ end }}`
+
+	commitCommentsEndpoint     = "https://api.github.com/repos/%s/commits/%s/comments"
+	deploymentStatusesEndpoint = "https://api.github.com/repos/%s/deployments/%d/statuses"
+
+	// deploymentStatusPending is the state of deployments without any
+	// status.
+	deploymentStatusPending = "pending"
+	// descriptionLength is the maximum length of deployment status
+	// descriptions accepted by GitHub.
+	descriptionLength = 140
+)
+
+// announcement is what announcement templates are rendered with.
+type announcement struct {
+	assignment.Assignment
+	// RevokeURL is the admin API URL of the assignment. It is empty unless
+	// the external URL is configured.
+	RevokeURL string
+}
+
+// announce tells the deployer on GitHub that the given assignment made them
+// on call, according to the announce mode. Pending assignments are announced
+// with their start.
+func (s *Service) announce(ctx context.Context, event DeploymentEvent, a assignment.Assignment) error {
+	if s.announceMode == AnnounceNone {
+		return nil
+	}
+
+	ctx, span := tracing.Start(ctx, "announce")
+	span.SetAttribute("mode", s.announceMode)
+
+	err := s.postAnnouncement(ctx, event, a)
+	span.End(err)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (s *Service) postAnnouncement(ctx context.Context, event DeploymentEvent, a assignment.Assignment) error {
+	data := announcement{
+		Assignment: a,
+	}
+	if s.externalURL != "" {
+		data.RevokeURL = fmt.Sprintf("%s/assignments/%s", s.externalURL, a.Name)
+	}
+
+	var buf bytes.Buffer
+	err := s.announceTemplate.Execute(&buf, data)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	message := strings.TrimSpace(buf.String())
+
+	switch s.announceMode {
+	case AnnounceComment:
+		commit := event.Deployment.SHA
+		if commit == "" {
+			commit = event.Deployment.Ref
+		}
+
+		err = s.github(ctx, event.owner(), "POST", fmt.Sprintf(commitCommentsEndpoint, event.Repository.FullName, commit), CommitComment{Body: message}, nil)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+	case AnnounceStatus:
+		if event.Deployment.ID == 0 {
+			return microerror.Maskf(executionFailedError, "deployment ID missing in payload")
+		}
+		url := fmt.Sprintf(deploymentStatusesEndpoint, event.Repository.FullName, event.Deployment.ID)
+
+		// A new status replaces the state shown for the deployment, so the
+		// latest one is reposted with the announcement as description.
+		var statuses []DeploymentStatus
+		err = s.github(ctx, event.owner(), "GET", url+"?per_page=1", nil, &statuses)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		status := DeploymentStatus{
+			State: deploymentStatusPending,
+		}
+		if len(statuses) > 0 {
+			status = statuses[0]
+		}
+		status.AutoInactive = false
+		status.Description = message
+		if r := []rune(message); len(r) > descriptionLength {
+			status.Description = string(r[:descriptionLength-3]) + "..."
+		}
+
+		err = s.github(ctx, event.owner(), "POST", url, status, nil)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// parseAnnounceTemplate parses the given announcement template, falling back
+// to DefaultAnnounceTemplate when it is empty. The template is rendered once
+// with an empty assignment, so that unknown fields are found at startup.
+func parseAnnounceTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultAnnounceTemplate
+	}
+
+	t, err := template.New("announce").Parse(text)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "announce template: %s", err.Error())
+	}
+	err = t.Execute(ioutil.Discard, announcement{})
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "announce template: %s", err.Error())
+	}
+
+	return t, nil
+}
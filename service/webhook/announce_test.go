@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+)
+
+func Test_DefaultAnnounceTemplate(t *testing.T) {
+	start := time.Date(2019, time.January, 1, 13, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		start    *time.Time
+		expected string
+	}{
+		{
+			name:     "case 0: assignment made",
+			expected: "@johndoe is on call for aws-operator on gauss until 2019-01-01 15:00 UTC. Revoke: https://auto-oncall.example.com/assignments/name",
+		},
+		{
+			name:     "case 1: assignment deferred",
+			start:    &start,
+			expected: "@johndoe is on call for aws-operator on gauss from 2019-01-01 13:00 UTC until 2019-01-01 15:00 UTC. Revoke: https://auto-oncall.example.com/assignments/name",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := parseAnnounceTemplate("")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a := assignment.New("aws-operator", "v1.0.0", "gauss", "johndoe", "john", start.Add(2*time.Hour))
+			a.Start = tc.start
+
+			var buf bytes.Buffer
+			err = tmpl.Execute(&buf, announcement{Assignment: a, RevokeURL: "https://auto-oncall.example.com/assignments/name"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tc.expected {
+				t.Fatalf("expected %#q, got %#q", tc.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/audit"
+	"github.com/giantswarm/auto-oncall/service/policy"
+)
+
+// actor is who caused a change of assignments.
+type actor struct {
+	// name identifies the actor within its origin, e.g. the creator of a
+	// deployment or the name of an admin token.
+	name   string
+	origin string
+}
+
+var reaper = actor{origin: audit.OriginReaper}
+
+// record writes the change of the given assignment to the audit log. Objects
+// of created assignments are recorded as created, those of deleted
+// assignments as deleted.
+func (s *Service) record(by actor, action string, a assignment.Assignment, reason string, err error) {
+	expiry := a.Expiry
+
+	e := audit.Entry{
+		Origin:  by.origin,
+		Actor:   by.name,
+		Action:  action,
+		Outcome: audit.OutcomeSucceeded,
+		Reason:  reason,
+
+		Assignment:   a.Name,
+		Delivery:     a.Delivery,
+		Organization: a.Organization,
+		Repository:   a.Repository,
+		Environment:  a.Environment,
+		Ref:          a.Ref,
+		GithubLogin:  a.GithubLogin,
+		Resolution:   a.Resolution,
+		User:         a.User,
+		Expiry:       &expiry,
+	}
+
+	if err != nil {
+		e.Outcome = audit.OutcomeFailed
+		if reason != "" {
+			e.Reason = reason + ": " + err.Error()
+		} else {
+			e.Reason = err.Error()
+		}
+	} else {
+		switch action {
+		case audit.ActionCreate:
+			e.Created = a.IDs
+		case audit.ActionDelete:
+			e.Deleted = a.IDs
+		}
+	}
+
+	s.audit.Record(e)
+}
+
+// recordPolicy writes the decision of the policy on the given assignment to
+// the audit log, before the assignment is made.
+func (s *Service) recordPolicy(by actor, a assignment.Assignment, d policy.Decision) {
+	expiry := a.Expiry
+
+	e := audit.Entry{
+		Origin:  by.origin,
+		Actor:   by.name,
+		Action:  audit.ActionPolicy,
+		Outcome: d.Action,
+		Reason:  d.Reason,
+
+		Delivery:     a.Delivery,
+		Organization: a.Organization,
+		Repository:   a.Repository,
+		Environment:  a.Environment,
+		Ref:          a.Ref,
+		GithubLogin:  a.GithubLogin,
+		Resolution:   a.Resolution,
+		User:         a.User,
+		Expiry:       &expiry,
+	}
+
+	s.audit.Record(e)
+}
@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// github sends a request to the GitHub API, authenticated with the GitHub
+// token of the given organization. The given body is sent as JSON unless it
+// is nil, and the response is decoded into the given result unless it is
+// nil. Responses other than 2xx are errors.
+func (s *Service) github(ctx context.Context, organization, method, url string, body, result interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, url, r)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	req = req.WithContext(ctx)
+
+	o, _ := s.organization(organization)
+	req.Header.Set("Authorization", fmt.Sprintf("token %s", o.GithubToken.Value()))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	start := time.Now()
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		githubRequestDuration.WithLabelValues("none").Observe(time.Since(start).Seconds())
+		return microerror.Mask(err)
+	}
+	githubRequestDuration.WithLabelValues(strconv.Itoa(resp.StatusCode)).Observe(time.Since(start).Seconds())
+
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err == nil {
+		githubRateLimitRemaining.Set(float64(remaining))
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return microerror.Maskf(executionFailedError, "%s %s: expected 2xx, got %d: %s", method, url, resp.StatusCode, b)
+	}
+
+	if result != nil {
+		err = json.Unmarshal(b, result)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
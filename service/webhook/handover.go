@@ -0,0 +1,281 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/audit"
+	"github.com/giantswarm/auto-oncall/service/notifier"
+	"github.com/giantswarm/auto-oncall/service/tracing"
+)
+
+const (
+	// HandoverKeep keeps the previous deployer on call until its assignment
+	// expires. The assignment of the new deployer is pending until then.
+	HandoverKeep = "keep"
+	// HandoverReplace deletes the assignments of previous deployers, so the
+	// latest deployer is on call alone.
+	HandoverReplace = "replace"
+	// HandoverShare pages the latest deployer together with previous
+	// deployers still on call.
+	HandoverShare = "share"
+)
+
+// assign creates the given assignment, taking over from assignments still
+// active for the same repository and environment according to the handover
+// mode. Assignments lasting until superseded are ended in any mode, pending
+// assignments are superseded by the given one. When the same user is already
+// on call for the repository and environment, their assignment is extended
+// instead. Previous assignments are only deleted once the given one is made,
+// failing to delete them does not fail the handover. It returns the resulting
+// assignment, or the assignment of the previous deployer staying on call with
+// handover mode keep. Changes are recorded in the audit log as caused by the
+// given actor.
+func (s *Service) assign(ctx context.Context, a assignment.Assignment, by actor) (assignment.Assignment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// At most one assignment is pending per repository and environment, the
+	// one of the latest deployment. A pending assignment being made is
+	// replaced when it is created or deferred again.
+	for _, p := range s.registry.Pending() {
+		if p.Organization != a.Organization || p.Repository != a.Repository || p.Environment != a.Environment || p.Name == a.Name {
+			continue
+		}
+
+		err := s.delete(ctx, p, by, fmt.Sprintf("superseded by %#q", a.Name))
+		if err != nil {
+			return assignment.Assignment{}, microerror.Mask(err)
+		}
+	}
+
+	existing, ok := s.registry.Find(a.Organization, a.Repository, a.Environment, a.User)
+	if ok {
+		if !a.Expiry.After(existing.Expiry) {
+			return existing, nil
+		}
+
+		existing.Ref = a.Ref
+		existing.Expiry = a.Expiry
+		err := s.extend(ctx, existing, by, "redeployed by the same user")
+		if err != nil {
+			return assignment.Assignment{}, microerror.Mask(err)
+		}
+
+		s.logger.Log("level", "info", "message", "extending assignment", "assignment", existing.Name, "user", existing.User, "until", existing.Expiry.Format(time.RFC3339))
+
+		return existing, nil
+	}
+
+	// Assignments lasting until superseded end with this deployment,
+	// whatever the handover mode.
+	var previous, superseded []assignment.Assignment
+	for _, p := range s.registry.Active(a.Organization, a.Repository, a.Environment) {
+		if p.UntilSuperseded {
+			superseded = append(superseded, p)
+		} else {
+			previous = append(previous, p)
+		}
+	}
+
+	var replaced []assignment.Assignment
+	switch {
+	case len(previous) == 0:
+		// There is nobody to hand over from.
+
+	case s.handover == HandoverKeep:
+		// The new deployer takes over once the last previous deployer is off
+		// call.
+		last := previous[0]
+		for _, p := range previous[1:] {
+			if p.Expiry.After(last.Expiry) {
+				last = p
+			}
+		}
+		// The previous deployer stays on call, so assignments lasting until
+		// superseded can be ended right away.
+		s.supersede(ctx, a, superseded, by)
+
+		until := last.Expiry
+		if !a.Expiry.After(until) {
+			s.logger.Log("level", "info", "message", "skipping assignment, previous deployer stays on call", "assignment", a.Name, "from", last.GithubLogin, "to", a.GithubLogin, "handover", s.handover, "until", until.Format(time.RFC3339))
+			return last, nil
+		}
+
+		// The assignment is kept pending in the registry and made by the
+		// reaper once due, handed over again like a new one, as the previous
+		// deployer may have been extended meanwhile.
+		a.Start = &until
+		err := s.registry.Add(a)
+		s.record(by, audit.ActionCreate, a, fmt.Sprintf("pending until %#q is off call", last.GithubLogin), err)
+		if err != nil {
+			return assignment.Assignment{}, microerror.Mask(err)
+		}
+
+		s.logger.Log("level", "info", "message", "deferring assignment until previous deployer is off call", "assignment", a.Name, "from", last.GithubLogin, "to", a.GithubLogin, "handover", s.handover, "until", until.Format(time.RFC3339))
+
+		return last, nil
+
+	case s.handover == HandoverShare:
+		seen := map[assignment.Responder]bool{{Type: assignment.ResponderUser, Name: a.User}: true}
+		for _, r := range a.Responders {
+			seen[r] = true
+		}
+		for _, p := range previous {
+			for _, r := range append([]assignment.Responder{{Type: assignment.ResponderUser, Name: p.User}}, p.Responders...) {
+				if seen[r] {
+					continue
+				}
+				seen[r] = true
+				a.Responders = append(a.Responders, r)
+			}
+		}
+		fallthrough
+
+	case s.handover == HandoverReplace:
+		replaced = previous
+	}
+
+	// Previous assignments are only deleted once the new one is made, so
+	// that somebody stays on call when making it fails.
+	a, err := s.create(ctx, a, by)
+	if err != nil {
+		return assignment.Assignment{}, microerror.Mask(err)
+	}
+
+	s.supersede(ctx, a, superseded, by)
+	for _, p := range replaced {
+		err := s.delete(ctx, p, by, fmt.Sprintf("handed over to %#q", a.Name))
+		if err != nil {
+			s.logger.Log("level", "error", "message", fmt.Sprintf("deleting assignment %#q handed over to %#q failed, it stays until it expires", p.Name, a.Name), "stack", fmt.Sprintf("%#v", err))
+			continue
+		}
+
+		s.logger.Log("level", "info", "message", "handing over assignment", "assignment", a.Name, "previous", p.Name, "from", p.GithubLogin, "to", a.GithubLogin, "handover", s.handover)
+	}
+
+	handedOver := append(superseded, replaced...)
+	if len(handedOver) > 0 {
+		s.notify(ctx, notifier.EventHandedOver, a, handedOver, by)
+	} else {
+		s.notify(ctx, notifier.EventCreated, a, nil, by)
+	}
+
+	return a, nil
+}
+
+// supersede deletes the given assignments lasting until superseded, which the
+// given assignment supersedes. Failures are logged and recorded in the audit
+// log, the assignments stay until they expire then.
+func (s *Service) supersede(ctx context.Context, a assignment.Assignment, superseded []assignment.Assignment, by actor) {
+	for _, p := range superseded {
+		err := s.delete(ctx, p, by, fmt.Sprintf("superseded by %#q", a.Name))
+		if err != nil {
+			s.logger.Log("level", "error", "message", fmt.Sprintf("deleting assignment %#q superseded by %#q failed, it stays until it expires", p.Name, a.Name), "stack", fmt.Sprintf("%#v", err))
+			continue
+		}
+
+		s.logger.Log("level", "info", "message", "superseding assignment", "assignment", a.Name, "previous", p.Name, "from", p.GithubLogin, "to", a.GithubLogin)
+	}
+}
+
+// create creates the given assignment in the provider and registers it. It
+// returns the assignment with the IDs of the created objects.
+func (s *Service) create(ctx context.Context, a assignment.Assignment, by actor) (assignment.Assignment, error) {
+	ctx, span := startChange(ctx, audit.ActionCreate, a)
+	err := s.provider.Create(ctx, &a)
+	if err == nil {
+		err = s.registry.Add(a)
+	}
+	span.End(err)
+	s.record(by, audit.ActionCreate, a, "", err)
+	if err != nil {
+		s.notifyFailed(ctx, audit.ActionCreate, a, by, err)
+		return assignment.Assignment{}, microerror.Mask(err)
+	}
+
+	return a, nil
+}
+
+// extend moves the end of the given assignment in the provider and registry
+// to its expiry. Pending assignments are only changed in the registry.
+func (s *Service) extend(ctx context.Context, a assignment.Assignment, by actor, reason string) error {
+	ctx, span := startChange(ctx, audit.ActionExtend, a)
+	var err error
+	if !a.Pending() {
+		err = s.provider.Extend(ctx, a)
+	}
+	if err == nil {
+		err = s.registry.Add(a)
+	}
+	span.End(err)
+	s.record(by, audit.ActionExtend, a, reason, err)
+	if err != nil {
+		s.notifyFailed(ctx, audit.ActionExtend, a, by, err)
+		return microerror.Mask(err)
+	}
+	s.notify(ctx, notifier.EventExtended, a, nil, by)
+
+	return nil
+}
+
+// delete deletes the given assignment from the provider and registry.
+// Pending assignments are only removed from the registry.
+func (s *Service) delete(ctx context.Context, a assignment.Assignment, by actor, reason string) error {
+	ctx, span := startChange(ctx, audit.ActionDelete, a)
+	var err error
+	if !a.Pending() {
+		err = s.provider.Delete(ctx, a)
+	}
+	if err == nil {
+		err = s.registry.Remove(a.Name)
+	}
+	span.End(err)
+	s.record(by, audit.ActionDelete, a, reason, err)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// startChange starts the span of the given change of an assignment in the
+// provider and registry.
+func startChange(ctx context.Context, action string, a assignment.Assignment) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, "assignment."+action)
+	span.SetAttribute("assignment", a.Name)
+	span.SetAttribute("expiry", a.Expiry.Format(time.RFC3339))
+
+	return ctx, span
+}
+
+// notify tells the notifiers about the given change of an assignment.
+func (s *Service) notify(ctx context.Context, event string, a assignment.Assignment, previous []assignment.Assignment, by actor) {
+	e := notifier.Event{
+		Type:       event,
+		Assignment: a,
+		Previous:   previous,
+		Origin:     by.origin,
+		Actor:      by.name,
+	}
+
+	s.notifier.Notify(ctx, e)
+}
+
+// notifyFailed tells the notifiers that the given action on an assignment
+// failed.
+func (s *Service) notifyFailed(ctx context.Context, action string, a assignment.Assignment, by actor, err error) {
+	e := notifier.Event{
+		Type:       notifier.EventFailed,
+		Assignment: a,
+		Origin:     by.origin,
+		Actor:      by.name,
+		Reason:     fmt.Sprintf("%s: %s", action, err.Error()),
+	}
+
+	s.notifier.Notify(ctx, e)
+}
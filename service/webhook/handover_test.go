@@ -0,0 +1,299 @@
+package webhook
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/audit"
+	"github.com/giantswarm/auto-oncall/service/notifier"
+)
+
+// stubProvider records the assignments created and deleted in it. Creating
+// and deleting fail with the given errors.
+type stubProvider struct {
+	createErr error
+	deleteErr error
+
+	mutex   sync.Mutex
+	created []string
+	deleted []string
+}
+
+func (p *stubProvider) Create(ctx context.Context, a *assignment.Assignment) error {
+	if p.createErr != nil {
+		return p.createErr
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	a.SetID("stub", a.Name)
+	p.created = append(p.created, a.GithubLogin)
+
+	return nil
+}
+
+func (p *stubProvider) Extend(ctx context.Context, a assignment.Assignment) error {
+	return nil
+}
+
+func (p *stubProvider) Delete(ctx context.Context, a assignment.Assignment) error {
+	if p.deleteErr != nil {
+		return p.deleteErr
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.deleted = append(p.deleted, a.GithubLogin)
+
+	return nil
+}
+
+func (p *stubProvider) Reconcile(ctx context.Context, active []assignment.Assignment) ([]assignment.Assignment, error) {
+	return active, nil
+}
+
+func newTestHandoverService(t *testing.T, handover string, p *stubProvider) *Service {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := audit.New(audit.Config{Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := notifier.New(notifier.Config{Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Service{
+		audit:  a,
+		logger: logger,
+
+		handover: handover,
+		notifier: n,
+		provider: p,
+		registry: assignment.NewRegistry(),
+	}
+}
+
+func testAssignment(login string, expiry time.Time, responders ...string) assignment.Assignment {
+	a := assignment.New("aws-operator", "v1.0.0", "anteater", login, login, expiry)
+	for _, r := range responders {
+		a.Responders = append(a.Responders, assignment.Responder{Type: assignment.ResponderUser, Name: r})
+	}
+
+	return a
+}
+
+func logins(assignments []assignment.Assignment) []string {
+	l := []string{}
+	for _, a := range assignments {
+		l = append(l, a.GithubLogin)
+	}
+	sort.Strings(l)
+
+	return l
+}
+
+func Test_Service_assign(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name               string
+		handover           string
+		previous           assignment.Assignment
+		untilSuperseded    bool
+		assignment         assignment.Assignment
+		createErr          error
+		deleteErr          error
+		expectedErr        bool
+		expectedLogin      string
+		expectedResponders []assignment.Responder
+		expectedActive     []string
+		expectedPending    []string
+		expectedDeleted    []string
+	}{
+		{
+			name:            "case 0: replace deletes the previous assignment",
+			handover:        HandoverReplace,
+			previous:        testAssignment("johndoe", now.Add(time.Hour)),
+			assignment:      testAssignment("janedoe", now.Add(2*time.Hour)),
+			expectedLogin:   "janedoe",
+			expectedActive:  []string{"janedoe"},
+			expectedPending: []string{},
+			expectedDeleted: []string{"johndoe"},
+		},
+		{
+			name:          "case 1: share pages previous deployers and responders once",
+			handover:      HandoverShare,
+			previous:      testAssignment("johndoe", now.Add(time.Hour), "ops", "janedoe"),
+			assignment:    testAssignment("janedoe", now.Add(2*time.Hour), "ops"),
+			expectedLogin: "janedoe",
+			expectedResponders: []assignment.Responder{
+				{Type: assignment.ResponderUser, Name: "ops"},
+				{Type: assignment.ResponderUser, Name: "johndoe"},
+			},
+			expectedActive:  []string{"janedoe"},
+			expectedPending: []string{},
+			expectedDeleted: []string{"johndoe"},
+		},
+		{
+			name:            "case 2: keep defers the assignment until the previous deployer is off call",
+			handover:        HandoverKeep,
+			previous:        testAssignment("johndoe", now.Add(time.Hour)),
+			assignment:      testAssignment("janedoe", now.Add(2*time.Hour)),
+			expectedLogin:   "johndoe",
+			expectedActive:  []string{"johndoe"},
+			expectedPending: []string{"janedoe"},
+			expectedDeleted: []string{},
+		},
+		{
+			name:            "case 3: keep skips the assignment ending before the previous one",
+			handover:        HandoverKeep,
+			previous:        testAssignment("johndoe", now.Add(2*time.Hour)),
+			assignment:      testAssignment("janedoe", now.Add(time.Hour)),
+			expectedLogin:   "johndoe",
+			expectedActive:  []string{"johndoe"},
+			expectedPending: []string{},
+			expectedDeleted: []string{},
+		},
+		{
+			name:            "case 4: keep ends assignments lasting until superseded",
+			handover:        HandoverKeep,
+			previous:        testAssignment("johndoe", now.Add(2*time.Hour)),
+			untilSuperseded: true,
+			assignment:      testAssignment("janedoe", now.Add(time.Hour)),
+			expectedLogin:   "janedoe",
+			expectedActive:  []string{"janedoe"},
+			expectedPending: []string{},
+			expectedDeleted: []string{"johndoe"},
+		},
+		{
+			name:            "case 5: failing create keeps the previous deployer on call",
+			handover:        HandoverReplace,
+			previous:        testAssignment("johndoe", now.Add(time.Hour)),
+			assignment:      testAssignment("janedoe", now.Add(2*time.Hour)),
+			createErr:       microerror.New("create failed"),
+			expectedErr:     true,
+			expectedActive:  []string{"johndoe"},
+			expectedPending: []string{},
+			expectedDeleted: []string{},
+		},
+		{
+			name:            "case 6: failing delete does not fail the handover",
+			handover:        HandoverReplace,
+			previous:        testAssignment("johndoe", now.Add(time.Hour)),
+			assignment:      testAssignment("janedoe", now.Add(2*time.Hour)),
+			deleteErr:       microerror.New("delete failed"),
+			expectedLogin:   "janedoe",
+			expectedActive:  []string{"janedoe", "johndoe"},
+			expectedPending: []string{},
+			expectedDeleted: []string{},
+		},
+		{
+			name:            "case 7: same user is extended",
+			handover:        HandoverReplace,
+			previous:        testAssignment("johndoe", now.Add(time.Hour)),
+			assignment:      testAssignment("johndoe", now.Add(2*time.Hour)),
+			expectedLogin:   "johndoe",
+			expectedActive:  []string{"johndoe"},
+			expectedPending: []string{},
+			expectedDeleted: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &stubProvider{deleted: []string{}}
+			s := newTestHandoverService(t, tc.handover, p)
+
+			previous := tc.previous
+			previous.UntilSuperseded = tc.untilSuperseded
+			_, err := s.create(context.Background(), previous, reaper)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.createErr = tc.createErr
+			p.deleteErr = tc.deleteErr
+
+			a, err := s.assign(context.Background(), tc.assignment, reaper)
+			if tc.expectedErr && err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !tc.expectedErr && err != nil {
+				t.Fatalf("expected no error, got %#v", err)
+			}
+
+			if a.GithubLogin != tc.expectedLogin {
+				t.Fatalf("expected assignment of %#q, got %#q", tc.expectedLogin, a.GithubLogin)
+			}
+			if tc.expectedResponders != nil && !reflect.DeepEqual(a.Responders, tc.expectedResponders) {
+				t.Fatalf("expected responders %#v, got %#v", tc.expectedResponders, a.Responders)
+			}
+
+			active := logins(s.registry.All())
+			if !reflect.DeepEqual(active, tc.expectedActive) {
+				t.Fatalf("expected active %#v, got %#v", tc.expectedActive, active)
+			}
+			pending := logins(s.registry.Pending())
+			if !reflect.DeepEqual(pending, tc.expectedPending) {
+				t.Fatalf("expected pending %#v, got %#v", tc.expectedPending, pending)
+			}
+			if !reflect.DeepEqual(p.deleted, tc.expectedDeleted) {
+				t.Fatalf("expected deleted %#v, got %#v", tc.expectedDeleted, p.deleted)
+			}
+		})
+	}
+}
+
+func Test_Service_assign_StartPending(t *testing.T) {
+	p := &stubProvider{}
+	s := newTestHandoverService(t, HandoverKeep, p)
+
+	previous := testAssignment("johndoe", time.Now().Add(100*time.Millisecond))
+	_, err := s.create(context.Background(), previous, reaper)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deferred := testAssignment("janedoe", time.Now().Add(time.Hour))
+	_, err = s.assign(context.Background(), deferred, reaper)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending := s.registry.Pending()
+	if len(pending) != 1 || !pending[0].Start.Equal(previous.Expiry) {
+		t.Fatalf("expected assignment pending until %s, got %#v", previous.Expiry, pending)
+	}
+
+	// The previous deployer goes off call, so the reaper makes the pending
+	// assignment.
+	time.Sleep(200 * time.Millisecond)
+	s.reap(context.Background(), reaper)
+
+	active := s.registry.All()
+	if len(active) != 1 || active[0].Name != deferred.Name || active[0].Pending() || active[0].IDs["stub"] != deferred.Name {
+		t.Fatalf("expected assignment %#q made, got %#v", deferred.Name, active)
+	}
+	if len(s.registry.Pending()) != 0 {
+		t.Fatalf("expected no pending assignment, got %#v", s.registry.Pending())
+	}
+	expected := []string{"johndoe", "janedoe"}
+	if !reflect.DeepEqual(p.created, expected) {
+		t.Fatalf("expected created %#v, got %#v", expected, p.created)
+	}
+}
@@ -6,9 +6,9 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/giantswarm/microerror"
@@ -23,6 +23,8 @@ const (
 type Hook struct {
 	// ID specifies the ID of a github webhook request.
 	ID string
+	// Event is the type of the github webhook event, e.g. deployment.
+	Event string
 	// Event contains unmarshaled webhook.
 	DeploymentEvent DeploymentEvent
 	// Signature specifies the signature of a github webhook request.
@@ -33,8 +35,14 @@ type Hook struct {
 
 // NewHook returns a Hook from an incoming HTTP Request.
 func (s *Service) NewHook(req *http.Request) (hook Hook, err error) {
+	defer func() {
+		if err != nil {
+			hooksTotal.WithLabelValues(eventLabel(req.Header.Get("x-github-event")), outcomeInvalid).Inc()
+		}
+	}()
+
 	if !strings.EqualFold(req.Method, "POST") {
-		return Hook{}, microerror.Maskf(executionFailedError, fmt.Sprintf("%#q requests are not supported", req.Method))
+		return Hook{}, microerror.Maskf(executionFailedError, "%#q requests are not supported", req.Method)
 	}
 
 	if hook.Signature = req.Header.Get("x-hub-signature"); len(hook.Signature) == 0 {
@@ -45,20 +53,47 @@ func (s *Service) NewHook(req *http.Request) (hook Hook, err error) {
 		return Hook{}, microerror.Maskf(executionFailedError, "no event id found")
 	}
 
-	if signedBy(hook, s.webhookSecret) {
-		return Hook{}, microerror.Maskf(executionFailedError, "invalid signature found")
-	}
+	hook.Event = req.Header.Get("x-github-event")
 
 	hook.Payload, err = ioutil.ReadAll(req.Body)
 	if err != nil {
 		return Hook{}, microerror.Mask(err)
 	}
 
+	// The payload is decoded before its signature is verified, since the
+	// secret depends on the organization named in it.
 	err = json.Unmarshal(hook.Payload, &hook.DeploymentEvent)
 	if err != nil {
 		return Hook{}, microerror.Mask(err)
 	}
 
+	o, ok := s.organization(hook.DeploymentEvent.owner())
+	if !ok {
+		return Hook{}, microerror.Maskf(executionFailedError, "organization %#q not configured", hook.DeploymentEvent.owner())
+	}
+	i, ok := signedBy(hook, o.secrets())
+	if !ok {
+		return Hook{}, microerror.Maskf(executionFailedError, "invalid signature found")
+	}
+	signaturesTotal.WithLabelValues(o.Name, strconv.Itoa(i)).Inc()
+
+	return hook, nil
+}
+
+// NewHookFromPayload returns a Hook of the given event type from a raw
+// payload, without any verification. It is meant for simulating webhooks.
+func NewHookFromPayload(id, event string, payload []byte) (Hook, error) {
+	hook := Hook{
+		ID:      id,
+		Event:   event,
+		Payload: payload,
+	}
+
+	err := json.Unmarshal(payload, &hook.DeploymentEvent)
+	if err != nil {
+		return Hook{}, microerror.Mask(err)
+	}
+
 	return hook, nil
 }
 
@@ -68,17 +103,32 @@ func signBody(body, secret []byte) []byte {
 	return []byte(computed.Sum(nil))
 }
 
-// signedBy checks that the provided secret matches the hook Signature.
+// signedBy returns the index of the first of the provided secrets matching
+// the hook Signature. Secrets are tried in order, each compared in constant
+// time, so that a secret can be rotated while both are accepted. Empty
+// secrets are skipped.
 //
 // Implements validation described in github's documentation:
 // https://developer.github.com/webhooks/securing/
-func signedBy(h Hook, secret []byte) bool {
+func signedBy(h Hook, secrets []string) (int, bool) {
 	if len(h.Signature) != signatureLength || !strings.HasPrefix(h.Signature, signaturePrefix) {
-		return false
+		return 0, false
 	}
 
 	actual := make([]byte, 20)
-	hex.Decode(actual, []byte(h.Signature[5:]))
+	_, err := hex.Decode(actual, []byte(h.Signature[5:]))
+	if err != nil {
+		return 0, false
+	}
+
+	for i, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		if hmac.Equal(signBody(h.Payload, []byte(secret)), actual) {
+			return i, true
+		}
+	}
 
-	return hmac.Equal(signBody(h.Payload, secret), actual)
+	return 0, false
 }
@@ -0,0 +1,405 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/secret"
+)
+
+const (
+	testPayload      = `{"organization": {"login": "GiantSwarm"}, "repository": {"name": "aws-operator", "owner": {"login": "GiantSwarm"}}}`
+	testOtherPayload = `{"repository": {"name": "api", "owner": {"login": "other"}}}`
+)
+
+func sign(payload, key string) string {
+	mac := hmac.New(sha1.New, []byte(key))
+	mac.Write([]byte(payload))
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newTestService(t *testing.T, webhookSecret string, webhookSecrets []string, organizations []OrganizationConfig, users map[string]string) *Service {
+	logger, err := micrologger.New(micrologger.Config{IOWriter: ioutil.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var configured []Organization
+	for _, c := range organizations {
+		o, err := NewOrganization(logger, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		configured = append(configured, o)
+	}
+
+	githubToken, err := secret.New(secret.Config{Logger: logger, Name: "GitHub token", Value: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName, err := validateOrganizations(configured, githubToken, users)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := secret.New(secret.Config{Logger: logger, Name: "webhook secret", Value: webhookSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Service{
+		githubToken:    githubToken,
+		logger:         logger,
+		organizations:  byName,
+		users:          users,
+		webhookSecret:  s,
+		webhookSecrets: webhookSecrets,
+	}
+}
+
+func Test_Service_NewHook(t *testing.T) {
+	testCases := []struct {
+		name           string
+		webhookSecret  string
+		webhookSecrets []string
+		organizations  []OrganizationConfig
+		payload        string
+		signature      string
+		expectedErr    bool
+	}{
+		{
+			name:          "case 0: signed with the webhook secret",
+			webhookSecret: "new",
+			payload:       testPayload,
+			signature:     sign(testPayload, "new"),
+		},
+		{
+			name:           "case 1: signed with a further webhook secret",
+			webhookSecret:  "new",
+			webhookSecrets: []string{"old", "older"},
+			payload:        testPayload,
+			signature:      sign(testPayload, "older"),
+		},
+		{
+			name:           "case 2: signed with an unknown secret",
+			webhookSecret:  "new",
+			webhookSecrets: []string{"old"},
+			payload:        testPayload,
+			signature:      sign(testPayload, "unknown"),
+			expectedErr:    true,
+		},
+		{
+			name:          "case 3: malformed signature",
+			webhookSecret: "new",
+			payload:       testPayload,
+			signature:     "sha256=" + sign(testPayload, "new")[5:],
+			expectedErr:   true,
+		},
+		{
+			name:          "case 4: signed with the secret of the organization",
+			webhookSecret: "new",
+			organizations: []OrganizationConfig{
+				{Name: "giantswarm", WebhookSecret: "gs", WebhookSecrets: []string{"gs-old"}},
+			},
+			payload:   testPayload,
+			signature: sign(testPayload, "gs-old"),
+		},
+		{
+			name:          "case 5: organization signed with the webhook secret of the service",
+			webhookSecret: "new",
+			organizations: []OrganizationConfig{
+				{Name: "giantswarm", WebhookSecret: "gs"},
+			},
+			payload:     testPayload,
+			signature:   sign(testPayload, "new"),
+			expectedErr: true,
+		},
+		{
+			name:          "case 6: other organization signed with the webhook secret of the service",
+			webhookSecret: "new",
+			organizations: []OrganizationConfig{
+				{Name: "giantswarm", WebhookSecret: "gs"},
+			},
+			payload:   testOtherPayload,
+			signature: sign(testOtherPayload, "new"),
+		},
+		{
+			name: "case 7: other organization without webhook secret of the service",
+			organizations: []OrganizationConfig{
+				{Name: "giantswarm", WebhookSecret: "gs"},
+			},
+			payload:     testOtherPayload,
+			signature:   sign(testOtherPayload, ""),
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestService(t, tc.webhookSecret, tc.webhookSecrets, tc.organizations, nil)
+
+			req := httptest.NewRequest("POST", "/webhook", bytes.NewReader([]byte(tc.payload)))
+			req.Header.Set("X-GitHub-Delivery", "delivery")
+			req.Header.Set("X-GitHub-Event", deploymentEvent)
+			req.Header.Set("X-Hub-Signature", tc.signature)
+
+			hook, err := s.NewHook(req)
+			if tc.expectedErr && !IsExecutionFailed(err) {
+				t.Fatalf("expected execution failed error, got %#v", err)
+			}
+			if !tc.expectedErr && err != nil {
+				t.Fatalf("expected no error, got %#v", err)
+			}
+			if !tc.expectedErr && string(hook.Payload) != tc.payload {
+				t.Fatalf("expected payload %#q, got %#q", tc.payload, hook.Payload)
+			}
+		})
+	}
+}
+
+func Test_signedBy(t *testing.T) {
+	testCases := []struct {
+		name          string
+		webhookSecret string
+		key           string
+		expectedIndex int
+		expectedOK    bool
+	}{
+		{
+			name:          "case 0: signed with the webhook secret",
+			webhookSecret: "new",
+			key:           "new",
+			expectedIndex: 0,
+			expectedOK:    true,
+		},
+		{
+			name:          "case 1: signed with a further secret",
+			webhookSecret: "new",
+			key:           "older",
+			expectedIndex: 2,
+			expectedOK:    true,
+		},
+		{
+			name:          "case 2: signed with a further secret without webhook secret",
+			key:           "older",
+			expectedIndex: 2,
+			expectedOK:    true,
+		},
+		{
+			name:       "case 3: signed with the empty webhook secret",
+			key:        "",
+			expectedOK: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestService(t, tc.webhookSecret, []string{"old", "older"}, nil, nil)
+			o, ok := s.organization("other")
+			if !ok {
+				t.Fatal("expected organization to be handled")
+			}
+
+			h := Hook{Payload: []byte(testOtherPayload), Signature: sign(testOtherPayload, tc.key)}
+			i, ok := signedBy(h, o.secrets())
+			if ok != tc.expectedOK {
+				t.Fatalf("expected %t, got %t", tc.expectedOK, ok)
+			}
+			if ok && i != tc.expectedIndex {
+				t.Fatalf("expected index %d, got %d", tc.expectedIndex, i)
+			}
+		})
+	}
+}
+
+func Test_Service_NewHook_RotatedSecretFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auto-oncall-hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "webhook-secret")
+	err = ioutil.WriteFile(path, []byte("gs\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestService(t, "", nil, []OrganizationConfig{{Name: "giantswarm", WebhookSecretFile: path}}, nil)
+
+	newHook := func(key string) error {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewReader([]byte(testPayload)))
+		req.Header.Set("X-GitHub-Delivery", "delivery")
+		req.Header.Set("X-GitHub-Event", deploymentEvent)
+		req.Header.Set("X-Hub-Signature", sign(testPayload, key))
+
+		_, err := s.NewHook(req)
+		return err
+	}
+
+	err = newHook("gs")
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	// The file changes size, so that it is read again regardless of the
+	// resolution of its modification time.
+	err = ioutil.WriteFile(path, []byte("gs-rotated\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = newHook("gs-rotated")
+	if err != nil {
+		t.Fatalf("expected no error after rotation, got %#v", err)
+	}
+	err = newHook("gs")
+	if !IsExecutionFailed(err) {
+		t.Fatalf("expected execution failed error for the previous secret, got %#v", err)
+	}
+}
+
+func Test_Service_filter_Organization(t *testing.T) {
+	organizations := []OrganizationConfig{
+		{Name: "giantswarm", WebhookSecret: "gs", Repositories: []string{"*-operator"}},
+	}
+
+	testCases := []struct {
+		name           string
+		webhookSecret  string
+		owner          string
+		repository     string
+		environment    string
+		expectedFilter string
+	}{
+		{
+			name:          "case 0: allowed repository of a configured organization",
+			webhookSecret: "new",
+			owner:         "GiantSwarm",
+			repository:    "aws-operator",
+			environment:   "anteater",
+		},
+		{
+			name:           "case 1: repository not allowed for a configured organization",
+			webhookSecret:  "new",
+			owner:          "giantswarm",
+			repository:     "api",
+			environment:    "anteater",
+			expectedFilter: filterRepository,
+		},
+		{
+			name:          "case 2: unknown organization handled by the service",
+			webhookSecret: "new",
+			owner:         "other",
+			repository:    "api",
+			environment:   "anteater",
+		},
+		{
+			name:           "case 3: unknown organization without webhook secret of the service",
+			owner:          "other",
+			repository:     "api",
+			environment:    "anteater",
+			expectedFilter: filterOrganization,
+		},
+		{
+			name:           "case 4: test environment of a configured organization",
+			owner:          "giantswarm",
+			repository:     "aws-operator",
+			environment:    testEnvironmentPrefix + "anteater",
+			expectedFilter: filterTestEnvironment,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestService(t, tc.webhookSecret, nil, organizations, nil)
+
+			h := Hook{Event: deploymentEvent}
+			h.DeploymentEvent.Repository.Owner.Login = tc.owner
+			h.DeploymentEvent.Repository.Name = tc.repository
+			h.DeploymentEvent.Deployment.Environment = tc.environment
+
+			filter, _ := s.filter(h)
+			if filter != tc.expectedFilter {
+				t.Fatalf("expected filter %#q, got %#q", tc.expectedFilter, filter)
+			}
+		})
+	}
+}
+
+func Test_Service_mapUser_Organization(t *testing.T) {
+	organizations := []OrganizationConfig{
+		{Name: "giantswarm", WebhookSecret: "gs", Users: map[string]string{"johndoe": "john@giantswarm.io"}},
+		{Name: "customer", WebhookSecret: "customer"},
+	}
+	users := map[string]string{
+		"johndoe": "john@example.com",
+		"janedoe": "jane@example.com",
+	}
+
+	testCases := []struct {
+		name         string
+		owner        string
+		githubLogin  string
+		expectedUser string
+		expectedErr  bool
+	}{
+		{
+			name:         "case 0: user mapping of the organization",
+			owner:        "GiantSwarm",
+			githubLogin:  "johndoe",
+			expectedUser: "john@giantswarm.io",
+		},
+		{
+			name:        "case 1: login only in the user mapping of the service",
+			owner:       "giantswarm",
+			githubLogin: "janedoe",
+			expectedErr: true,
+		},
+		{
+			name:         "case 2: organization without user mapping",
+			owner:        "customer",
+			githubLogin:  "janedoe",
+			expectedUser: "jane@example.com",
+		},
+		{
+			name:         "case 3: unknown organization",
+			owner:        "other",
+			githubLogin:  "johndoe",
+			expectedUser: "john@example.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestService(t, "new", nil, organizations, users)
+
+			o, ok := s.organization(tc.owner)
+			if !ok {
+				t.Fatalf("expected organization %#q to be handled", tc.owner)
+			}
+
+			user, err := s.mapUser(context.Background(), o, tc.githubLogin)
+			if tc.expectedErr && !IsUserNotFound(err) {
+				t.Fatalf("expected user not found error, got %#v", err)
+			}
+			if !tc.expectedErr && err != nil {
+				t.Fatalf("expected no error, got %#v", err)
+			}
+			if user != tc.expectedUser {
+				t.Fatalf("expected user %#q, got %#q", tc.expectedUser, user)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+)
+
+const (
+	namespace = "auto_oncall"
+)
+
+const (
+	outcomeAssigned = "assigned"
+	outcomeDropped  = "dropped"
+	outcomeFailed   = "failed"
+	outcomeInvalid  = "invalid"
+	outcomeSkipped  = "skipped"
+)
+
+const (
+	// eventOther is the event label of webhooks of unknown event types.
+	eventOther = "other"
+)
+
+// knownEvents are the GitHub event types used as event label as given.
+var knownEvents = map[string]bool{
+	deploymentEvent:     true,
+	"deployment_status": true,
+	"ping":              true,
+	"push":              true,
+	"status":            true,
+}
+
+var (
+	hooksTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "hooks_total",
+			Help:      "Number of received GitHub webhooks by event type and outcome.",
+		},
+		[]string{"event", "outcome"},
+	)
+	signaturesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "signatures_total",
+			Help:      "Number of verified GitHub webhooks by organization and matching webhook secret, 0 for the webhook secret and 1 onwards for further secrets.",
+		},
+		[]string{"organization", "secret"},
+	)
+	filteredTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "filtered_total",
+			Help:      "Number of GitHub webhooks skipped by reason.",
+		},
+		[]string{"reason"},
+	)
+	authorResolutionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "author_resolutions_total",
+			Help:      "Number of resolved deployment authors by source, either the deployment creator or the deployed commit.",
+		},
+		[]string{"source"},
+	)
+	unmappedUsersTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "webhook",
+			Name:      "unmapped_users_total",
+			Help:      "Number of deployment authors not found in the user mapping.",
+		},
+	)
+
+	githubRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "github",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of GitHub API requests by status code.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"code"},
+	)
+	githubRateLimitRemaining = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "github",
+			Name:      "rate_limit_remaining",
+			Help:      "Number of GitHub API requests remaining in the current rate limit window.",
+		},
+	)
+
+	activeAssignmentsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "active_assignments"),
+		"Number of currently active assignments by environment.",
+		[]string{"environment"},
+		nil,
+	)
+)
+
+// eventLabel returns the event label of the given event type. The event type
+// is taken from a request header, which is not covered by the signature, so
+// unknown types are counted as eventOther to keep the number of series
+// bounded.
+func eventLabel(event string) string {
+	if knownEvents[event] {
+		return event
+	}
+	return eventOther
+}
+
+func init() {
+	prometheus.MustRegister(hooksTotal)
+	prometheus.MustRegister(signaturesTotal)
+	prometheus.MustRegister(filteredTotal)
+	prometheus.MustRegister(authorResolutionsTotal)
+	prometheus.MustRegister(unmappedUsersTotal)
+	prometheus.MustRegister(githubRequestDuration)
+	prometheus.MustRegister(githubRateLimitRemaining)
+}
+
+// activeCollector exposes the active assignments of the registry, counted
+// when scraped.
+type activeCollector struct {
+	registry *assignment.Registry
+}
+
+func (c *activeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeAssignmentsDesc
+}
+
+func (c *activeCollector) Collect(ch chan<- prometheus.Metric) {
+	counts := map[string]int{}
+	for _, a := range c.registry.All() {
+		counts[a.Environment]++
+	}
+
+	for environment, n := range counts {
+		ch <- prometheus.MustNewConstMetric(activeAssignmentsDesc, prometheus.GaugeValue, float64(n), environment)
+	}
+}
@@ -0,0 +1,204 @@
+package webhook
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/auto-oncall/service/secret"
+)
+
+// Organization is a GitHub organization with its own webhook, GitHub
+// credentials and user mapping.
+type Organization struct {
+	// Name is the login of the organization, e.g. giantswarm.
+	Name string
+	// WebhookSecret is the secret of the organization webhook.
+	WebhookSecret *secret.Secret
+	// WebhookSecrets are further secrets accepted for the organization
+	// webhook, tried in order after WebhookSecret, e.g. while rotating it.
+	WebhookSecrets []string
+	// GithubToken is used for requests concerning repositories of the
+	// organization. It defaults to the GitHub token of the service, looked
+	// up when used.
+	GithubToken *secret.Secret
+	// Repositories are the shell patterns of the repositories deployments are
+	// handled for. All repositories are handled when empty.
+	Repositories []string
+	// Team is the team owning the objects created in the backend for
+	// assignments of the organization, e.g. the Opsgenie team. The team of
+	// the provider is used when empty.
+	Team string
+	// Users maps GitHub logins to users of the provider. The user mapping of
+	// the service is used when empty.
+	Users map[string]string
+}
+
+// OrganizationConfig is an organization as configured in the secret file.
+// Its webhook secret and GitHub token may be read from their own files
+// instead, see Organization for the other fields.
+type OrganizationConfig struct {
+	Name              string
+	WebhookSecret     string
+	WebhookSecretFile string
+	WebhookSecrets    []string
+	GithubToken       string
+	GithubTokenFile   string
+	Repositories      []string
+	Team              string
+	Users             map[string]string
+}
+
+// NewOrganization creates the organization of the given configuration. It
+// fails when one of its secret files cannot be read.
+func NewOrganization(logger micrologger.Logger, config OrganizationConfig) (Organization, error) {
+	o := Organization{
+		Name:           config.Name,
+		WebhookSecrets: config.WebhookSecrets,
+		Repositories:   config.Repositories,
+		Team:           config.Team,
+		Users:          config.Users,
+	}
+
+	var err error
+	{
+		c := secret.Config{
+			Logger: logger,
+
+			Name:  fmt.Sprintf("webhook secret of organization %#q", config.Name),
+			Path:  config.WebhookSecretFile,
+			Value: config.WebhookSecret,
+		}
+
+		o.WebhookSecret, err = secret.New(c)
+		if err != nil {
+			return Organization{}, microerror.Mask(err)
+		}
+	}
+
+	{
+		c := secret.Config{
+			Logger: logger,
+
+			Name:  fmt.Sprintf("GitHub token of organization %#q", config.Name),
+			Path:  config.GithubTokenFile,
+			Value: config.GithubToken,
+		}
+
+		o.GithubToken, err = secret.New(c)
+		if err != nil {
+			return Organization{}, microerror.Mask(err)
+		}
+	}
+
+	return o, nil
+}
+
+// validateOrganizations checks the given organizations, fills in defaults
+// and returns them by lower case name, as organization logins are case
+// insensitive.
+func validateOrganizations(organizations []Organization, githubToken *secret.Secret, users map[string]string) (map[string]Organization, error) {
+	byName := map[string]Organization{}
+	for i, o := range organizations {
+		if o.Name == "" {
+			return nil, microerror.Maskf(invalidConfigError, "organization %d: name must not be empty", i)
+		}
+		if _, ok := byName[strings.ToLower(o.Name)]; ok {
+			return nil, microerror.Maskf(invalidConfigError, "organization %#q configured twice", o.Name)
+		}
+		secrets, err := webhookSecrets(o.WebhookSecret.Value(), o.WebhookSecrets)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "organization %#q: %s", o.Name, err.Error())
+		}
+		if len(secrets) == 0 {
+			return nil, microerror.Maskf(invalidConfigError, "organization %#q: webhook secret must not be empty", o.Name)
+		}
+		for _, pattern := range o.Repositories {
+			_, err := path.Match(pattern, "")
+			if err != nil {
+				return nil, microerror.Maskf(invalidConfigError, "organization %#q: invalid repository pattern %#q", o.Name, pattern)
+			}
+		}
+
+		if o.GithubToken.Value() == "" && githubToken.Value() == "" {
+			return nil, microerror.Maskf(invalidConfigError, "organization %#q: GitHub token must not be empty", o.Name)
+		}
+		if len(o.Users) == 0 {
+			o.Users = users
+		}
+
+		byName[strings.ToLower(o.Name)] = o
+	}
+
+	return byName, nil
+}
+
+// organization returns the configuration of the given organization. Hooks of
+// organizations not configured explicitly are handled with the webhook
+// secrets, GitHub token and user mapping of the service, if there is a
+// webhook secret. The name of the returned organization is empty then.
+// Secrets are looked up when used, as they may change while running.
+func (s *Service) organization(name string) (Organization, bool) {
+	o, ok := s.organizations[strings.ToLower(name)]
+	if !ok {
+		if s.webhookSecret.Value() == "" && len(s.webhookSecrets) == 0 {
+			return Organization{}, false
+		}
+
+		o = Organization{
+			WebhookSecret:  s.webhookSecret,
+			WebhookSecrets: s.webhookSecrets,
+			Users:          s.users,
+		}
+	}
+	if o.GithubToken.Value() == "" {
+		o.GithubToken = s.githubToken
+	}
+
+	return o, true
+}
+
+// allows tells whether deployments of the given repository are handled for
+// the organization.
+func (o Organization) allows(repository string) bool {
+	if len(o.Repositories) == 0 {
+		return true
+	}
+
+	for _, pattern := range o.Repositories {
+		ok, _ := path.Match(pattern, repository)
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// secrets returns the webhook secrets of the organization in the order they
+// are tried, looking up the current value of WebhookSecret. WebhookSecret
+// comes first even when empty, so that further secrets keep their position
+// whether or not it is set.
+func (o Organization) secrets() []string {
+	return append([]string{o.WebhookSecret.Value()}, o.WebhookSecrets...)
+}
+
+// webhookSecrets returns the accepted webhook secrets in the order they are
+// tried, the given secret first, if any. Further secrets must not be empty.
+func webhookSecrets(secret string, further []string) ([]string, error) {
+	var secrets []string
+	if secret != "" {
+		secrets = append(secrets, secret)
+	}
+	for i, s := range further {
+		if s == "" {
+			return nil, microerror.Maskf(invalidConfigError, "webhook secret %d must not be empty", i)
+		}
+		secrets = append(secrets, s)
+	}
+
+	return secrets, nil
+}
@@ -0,0 +1,225 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/policy"
+	"github.com/giantswarm/auto-oncall/service/tracing"
+	"github.com/giantswarm/auto-oncall/service/ttl"
+)
+
+const (
+	deploymentEvent = "deployment"
+
+	filterEvent           = "event"
+	filterOrganization    = "organization"
+	filterPolicy          = "policy"
+	filterRepository      = "repository"
+	filterTestEnvironment = "test_environment"
+
+	resolutionCommit  = "commit"
+	resolutionCreator = "creator"
+)
+
+// Decision is the outcome of processing a webhook: either the assignment to
+// create, or the reason the webhook is skipped.
+type Decision struct {
+	// Skipped tells whether no assignment is made. Reason tells why.
+	Skipped bool   `json:"skipped"`
+	Reason  string `json:"reason,omitempty"`
+	// GithubLogin is the resolved author of the deployment.
+	GithubLogin string `json:"githubLogin,omitempty"`
+	// User is the author as configured in the user mapping.
+	User string `json:"user,omitempty"`
+	// Assignment is the assignment to create, unless skipped. When skipped
+	// by the policy, it is the assignment that would have been created.
+	Assignment *assignment.Assignment `json:"assignment,omitempty"`
+	// TTL is the decision on how long the author stays on call.
+	TTL *ttl.Decision `json:"ttl,omitempty"`
+	// Policy are the decisions of the policy on the assignment, if it applies
+	// to the deployer.
+	Policy []policy.Decision `json:"policy,omitempty"`
+}
+
+// Decide runs the processing pipeline of the given webhook without creating
+// anything: it filters the event, resolves the author of the deployment, maps
+// the author to a user, decides on the TTL, constructs the assignment and
+// applies the policy to it. Decisions made before an error are returned
+// together with the error. Each stage is traced as child of the span in the
+// given context.
+func (s *Service) Decide(ctx context.Context, h Hook) (Decision, error) {
+	var d Decision
+
+	_, span := tracing.Start(ctx, "filter")
+	filtered, reason := s.filter(h)
+	span.SetAttribute("filter", filtered)
+	span.End(nil)
+	if filtered != "" {
+		filteredTotal.WithLabelValues(filtered).Inc()
+		d.Skipped = true
+		d.Reason = reason
+		return d, nil
+	}
+
+	o, _ := s.organization(h.DeploymentEvent.owner())
+
+	githubLogin, resolution, err := s.resolveAuthor(ctx, h.DeploymentEvent)
+	if err != nil {
+		return d, microerror.Mask(err)
+	}
+	d.GithubLogin = githubLogin
+
+	user, err := s.mapUser(ctx, o, githubLogin)
+	if err != nil {
+		return d, microerror.Mask(err)
+	}
+	d.User = user
+
+	expiresIn, ttlDecision := s.decideTTL(ctx, h.DeploymentEvent)
+	d.TTL = &ttlDecision
+
+	a := newAssignment(h, o, githubLogin, resolution, user, expiresIn, ttlDecision.UntilSuperseded)
+	d.Assignment = &a
+
+	d.Policy = s.applyPolicy(ctx, &a)
+	// The policy maps backups with the user mapping of the service, the one
+	// of the organization takes precedence.
+	if u, ok := o.Users[a.GithubLogin]; ok && a.Resolution == policy.ResolutionBackup {
+		a.User = u
+	}
+	for _, p := range d.Policy {
+		if p.Action == policy.ActionSkip {
+			filteredTotal.WithLabelValues(filterPolicy).Inc()
+			d.Skipped = true
+			d.Reason = p.Reason
+		}
+	}
+
+	return d, nil
+}
+
+// Simulate decides on the given webhook like Process and renders the
+// assignment in the provider, without handing over from or registering
+// assignments. It is meant to be used with a provider whose requests are not
+// actually sent.
+func (s *Service) Simulate(ctx context.Context, h Hook) (Decision, error) {
+	d, err := s.Decide(ctx, h)
+	if err != nil {
+		return d, microerror.Mask(err)
+	}
+	if d.Skipped {
+		return d, nil
+	}
+
+	err = s.provider.Create(ctx, d.Assignment)
+	if err != nil {
+		return d, microerror.Mask(err)
+	}
+
+	return d, nil
+}
+
+// filter returns the filter skipping the given webhook, if any, together with
+// a description of the reason.
+func (s *Service) filter(h Hook) (string, string) {
+	if h.Event != deploymentEvent {
+		return filterEvent, fmt.Sprintf("ignoring %#q event", h.Event)
+	}
+	o, ok := s.organization(h.DeploymentEvent.owner())
+	if !ok {
+		return filterOrganization, fmt.Sprintf("ignoring organization %#q not configured", h.DeploymentEvent.owner())
+	}
+	if !o.allows(h.DeploymentEvent.Repository.Name) {
+		return filterRepository, fmt.Sprintf("ignoring repository %#q not allowed for organization %#q", h.DeploymentEvent.Repository.Name, o.Name)
+	}
+	if strings.HasPrefix(h.DeploymentEvent.Deployment.Environment, testEnvironmentPrefix) {
+		return filterTestEnvironment, "ignoring test environment"
+	}
+
+	return "", ""
+}
+
+// resolveAuthor returns the GitHub login of the author of the deployment and
+// how it was resolved. For deployments created by the bot account the author
+// of the deployed commit is used, the creator of the deployment otherwise.
+func (s *Service) resolveAuthor(ctx context.Context, event DeploymentEvent) (login string, resolution string, err error) {
+	ctx, span := tracing.Start(ctx, "resolve_author")
+	defer func() {
+		span.SetAttribute("github.login", login)
+		span.SetAttribute("resolution", resolution)
+		span.End(err)
+	}()
+
+	if event.Deployment.Creator.Login != botAccount {
+		authorResolutionsTotal.WithLabelValues(resolutionCreator).Inc()
+		return event.Deployment.Creator.Login, resolutionCreator, nil
+	}
+
+	commit := Commit{}
+	err = s.github(ctx, event.owner(), "GET", fmt.Sprintf(commitEndpoint, event.Repository.FullName, event.Deployment.Ref), nil, &commit)
+	if err != nil {
+		return "", "", microerror.Mask(err)
+	}
+
+	authorResolutionsTotal.WithLabelValues(resolutionCommit).Inc()
+
+	return commit.Author.Login, resolutionCommit, nil
+}
+
+// mapUser returns the user configured for the given GitHub login in the user
+// mapping of the given organization.
+func (s *Service) mapUser(ctx context.Context, o Organization, githubLogin string) (string, error) {
+	_, span := tracing.Start(ctx, "map_user")
+	span.SetAttribute("github.login", githubLogin)
+
+	user, ok := o.Users[githubLogin]
+	if !ok {
+		unmappedUsersTotal.Inc()
+		err := microerror.Maskf(userNotFoundError, "%#q", githubLogin)
+		span.End(err)
+		return "", err
+	}
+	span.SetAttribute("user", user)
+	span.End(nil)
+
+	return user, nil
+}
+
+// applyPolicy applies the policy to the given assignment. It returns the
+// decisions, none if the policy does not apply to the deployer.
+func (s *Service) applyPolicy(ctx context.Context, a *assignment.Assignment) []policy.Decision {
+	ctx, span := tracing.Start(ctx, "policy")
+	defer span.End(nil)
+
+	decisions := s.policy.Apply(ctx, a, a.Created)
+	var actions []string
+	for _, d := range decisions {
+		actions = append(actions, d.Action)
+	}
+	if len(actions) > 0 {
+		span.SetAttribute("action", strings.Join(actions, ","))
+	}
+
+	return decisions
+}
+
+// newAssignment constructs the assignment of the deployment of the given
+// webhook of the given organization, expiring in the given duration.
+func newAssignment(h Hook, o Organization, githubLogin, resolution, user string, expiresIn time.Duration, untilSuperseded bool) assignment.Assignment {
+	event := h.DeploymentEvent
+
+	a := assignment.New(event.Repository.Name, event.Deployment.Ref, event.Deployment.Environment, githubLogin, user, time.Now().Add(expiresIn))
+	a.Organization = o.Name
+	a.Team = o.Team
+	a.Delivery = h.ID
+	a.Resolution = resolution
+	a.UntilSuperseded = untilSuperseded
+
+	return a
+}
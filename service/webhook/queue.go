@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/auto-oncall/service/tracing"
+)
+
+const (
+	// queueSize is the number of webhooks waiting to be processed at most.
+	queueSize = 100
+	// queueHighWatermark is the backlog from which on the service is not
+	// ready anymore, so that no more webhooks are sent its way.
+	queueHighWatermark = 80
+
+	rateLimitEndpoint = "https://api.github.com/rate_limit"
+)
+
+// queued is a webhook waiting to be processed, together with the context
+// carrying its trace.
+type queued struct {
+	ctx  context.Context
+	hook Hook
+}
+
+// Enqueue schedules the given webhook for processing. It fails when the
+// queue is full. The trace of the given context is continued when the webhook
+// is processed, while its cancellation is not.
+func (s *Service) Enqueue(ctx context.Context, h Hook) error {
+	select {
+	case s.queue <- queued{ctx: tracing.Detach(ctx), hook: h}:
+		return nil
+	default:
+		hooksTotal.WithLabelValues(eventLabel(h.Event), outcomeDropped).Inc()
+		return microerror.Maskf(queueFullError, "%d webhooks waiting", queueSize)
+	}
+}
+
+// Backlog returns the number of webhooks waiting to be processed.
+func (s *Service) Backlog() int {
+	return len(s.queue)
+}
+
+// CheckQueue fails when the backlog of webhooks reaches the high watermark.
+func (s *Service) CheckQueue() error {
+	backlog := s.Backlog()
+	if backlog >= queueHighWatermark {
+		return microerror.Maskf(executionFailedError, "%d of %d webhooks waiting to be processed", backlog, queueSize)
+	}
+
+	return nil
+}
+
+// CheckGithub verifies that GitHub is reachable and the GitHub tokens of the
+// service and all organizations are valid. Requests for the rate limit do not
+// count against it.
+func (s *Service) CheckGithub() error {
+	checked := map[string]bool{}
+	check := func(name, token string) error {
+		if token == "" || checked[token] {
+			return nil
+		}
+		checked[token] = true
+
+		req, err := http.NewRequest("GET", rateLimitEndpoint, nil)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("token %s", token))
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return microerror.Maskf(executionFailedError, "GET %s with token of %s: expected 200, got %d", rateLimitEndpoint, name, resp.StatusCode)
+		}
+
+		return nil
+	}
+
+	err := check("service", s.githubToken.Value())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	for _, o := range s.organizations {
+		err := check(fmt.Sprintf("organization %#q", o.Name), o.GithubToken.Value())
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// work processes queued webhooks one after another.
+func (s *Service) work() {
+	for q := range s.queue {
+		s.Process(q.ctx, q.hook)
+	}
+}
@@ -0,0 +1,162 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/notifier"
+)
+
+const (
+	reapInterval = time.Minute
+)
+
+// Boot starts processing queued webhooks, removing expired assignments from
+// the provider and, with a persistent registry, reconciling the provider with
+// the registry. It blocks forever.
+func (s *Service) Boot() {
+	go s.work()
+
+	err := prometheus.Register(&activeCollector{registry: s.registry})
+	if err != nil {
+		s.logger.Log("level", "error", "message", "registering active assignments collector failed", "stack", fmt.Sprintf("%#v", err))
+	}
+
+	var reconcile <-chan time.Time
+	if s.registry.Persistent() {
+		s.reconcile()
+		reconcile = time.Tick(s.reconcileInterval)
+	}
+	reap := time.Tick(reapInterval)
+
+	for {
+		select {
+		case <-reap:
+			s.reap(context.Background(), reaper)
+		case <-reconcile:
+			s.reconcile()
+		}
+	}
+}
+
+// reap deletes expired assignments and makes pending assignments which are
+// due. It returns how many assignments were deleted. Assignments failing to
+// be deleted or made are retried with the next run.
+func (s *Service) reap(ctx context.Context, by actor) int {
+	deleted := s.deleteExpired(ctx, by)
+	s.startPending(ctx, by)
+
+	return deleted
+}
+
+func (s *Service) deleteExpired(ctx context.Context, by actor) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var deleted int
+	for _, a := range s.registry.Expired() {
+		reason := "expired"
+		if a.Pending() {
+			reason = "expired while pending"
+		}
+
+		err := s.delete(ctx, a, by, reason)
+		if err != nil {
+			s.logger.Log("level", "error", "message", fmt.Sprintf("deleting expired assignment %#q failed", a.Name), "stack", fmt.Sprintf("%#v", err))
+			continue
+		}
+
+		s.logger.Log("level", "info", "message", "deleted expired assignment", "assignment", a.Name, "user", a.User)
+		// Nobody was on call for pending assignments, so there is nothing to
+		// tell.
+		if !a.Pending() {
+			s.notify(ctx, notifier.EventExpired, a, nil, by)
+		}
+		deleted++
+	}
+
+	return deleted
+}
+
+// startPending makes the pending assignments whose start is due. They are
+// handed over like new assignments, so they may be deferred again.
+func (s *Service) startPending(ctx context.Context, by actor) {
+	now := time.Now()
+	for _, p := range s.registry.Pending() {
+		if p.Start.After(now) {
+			break
+		}
+
+		a := p
+		a.Start = nil
+		result, err := s.assign(ctx, a, by)
+		if err != nil {
+			s.logger.Log("level", "error", "message", fmt.Sprintf("making pending assignment %#q failed", p.Name), "stack", fmt.Sprintf("%#v", err))
+			continue
+		}
+
+		err = s.dropPending(p)
+		if err != nil {
+			s.logger.Log("level", "error", "message", fmt.Sprintf("removing pending assignment %#q failed", p.Name), "stack", fmt.Sprintf("%#v", err))
+			continue
+		}
+
+		s.logger.Log("level", "info", "message", "made pending assignment", "assignment", p.Name, "result", result.Name, "user", result.User)
+	}
+}
+
+// dropPending removes the given pending assignment from the registry if it
+// is still pending as it was, i.e. it was neither created nor deferred again
+// but skipped, e.g. because the same user is on call already.
+func (s *Service) dropPending(p assignment.Assignment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, ok := s.registry.Get(p.Name)
+	if !ok || !current.Pending() || !current.Start.Equal(*p.Start) {
+		return nil
+	}
+
+	err := s.registry.Remove(p.Name)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// reconcile makes the provider match the active assignments of the registry.
+func (s *Service) reconcile() {
+	err := s.reconcileProvider()
+	if err != nil {
+		s.logger.Log("level", "error", "message", "reconciling provider failed", "stack", fmt.Sprintf("%#v", err))
+	}
+}
+
+func (s *Service) reconcileProvider() error {
+	// Assignments created or deleted while reconciling could be undone by
+	// the reconciliation, so webhooks are not processed meanwhile.
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	active, err := s.provider.Reconcile(context.Background(), s.registry.All())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, a := range active {
+		err = s.registry.Add(a)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	s.logger.Log("level", "debug", "message", fmt.Sprintf("reconciled %d active assignments", len(active)))
+
+	return nil
+}
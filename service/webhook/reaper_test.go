@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+)
+
+// reconcilingProvider recreates the objects of all assignments it reconciles
+// under new IDs.
+type reconcilingProvider struct {
+	stubProvider
+}
+
+func (p *reconcilingProvider) Reconcile(ctx context.Context, active []assignment.Assignment) ([]assignment.Assignment, error) {
+	var reconciled []assignment.Assignment
+	for _, a := range active {
+		a.SetID("stub", "recreated")
+		reconciled = append(reconciled, a)
+	}
+
+	return reconciled, nil
+}
+
+func Test_Service_reconcileProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auto-oncall-reaper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "assignments.json")
+
+	s := newTestHandoverService(t, HandoverReplace, &stubProvider{})
+	s.registry, err = assignment.NewFileRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := s.create(context.Background(), testAssignment("johndoe", time.Now().Add(time.Hour)), reaper)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The service restarts with the stored assignments, while the objects
+	// were lost in the provider.
+	s.provider = &reconcilingProvider{}
+	s.registry, err = assignment.NewFileRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.reconcileProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := assignment.NewFileRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reconciled, ok := r.Get(a.Name)
+	if !ok || reconciled.IDs["stub"] != "recreated" {
+		t.Fatalf("expected assignment %#q stored with recreated objects, got %#v", a.Name, reconciled)
+	}
+}
@@ -1,156 +1,242 @@
 package webhook
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
-	"github.com/giantswarm/opsctl/service/opsgenie"
+
+	"github.com/giantswarm/auto-oncall/service/assignment"
+	"github.com/giantswarm/auto-oncall/service/audit"
+	"github.com/giantswarm/auto-oncall/service/notifier"
+	"github.com/giantswarm/auto-oncall/service/policy"
+	"github.com/giantswarm/auto-oncall/service/provider"
+	"github.com/giantswarm/auto-oncall/service/secret"
+	"github.com/giantswarm/auto-oncall/service/tracing"
+	"github.com/giantswarm/auto-oncall/service/ttl"
 )
 
 const (
 	botAccount            = "taylorbot"
 	commitEndpoint        = "https://api.github.com/repos/%s/commits/%s"
 	testEnvironmentPrefix = "g"
-	routingRuleTTL        = time.Hour * time.Duration(1)
-	routingRuleType       = "match-all-conditions"
 )
 
 type Config struct {
+	// Audit records changes of assignments.
+	Audit      *audit.Service
 	HttpClient *http.Client
 	Logger     micrologger.Logger
 
-	GithubToken   string
-	Opsgenie      *opsgenie.OpsGenie
-	Users         map[string]string
-	WebhookSecret string
+	// AnnounceMode tells how assignments are announced on GitHub. It is one
+	// of AnnounceNone, AnnounceComment or AnnounceStatus.
+	AnnounceMode string
+	// AnnounceTemplate is the text/template announcements are rendered
+	// from. It defaults to DefaultAnnounceTemplate.
+	AnnounceTemplate string
+	// ExternalURL is the URL auto-oncall is reachable at, used for links to
+	// assignments. Links are left out when it is empty.
+	ExternalURL string
+	// GithubToken is used for GitHub requests of organizations without their
+	// own token. It may be read from a file and change while running.
+	GithubToken *secret.Secret
+	// Handover is the handover mode used when a repository is deployed to an
+	// environment while earlier assignments are still active. It is one of
+	// HandoverKeep, HandoverReplace or HandoverShare.
+	Handover string
+	// Notifier is told about changes of assignments.
+	Notifier *notifier.Service
+	// Organizations are the GitHub organizations with their own webhook
+	// secret, GitHub token and user mapping. Hooks of other organizations
+	// are handled with WebhookSecret, GithubToken and Users, unless there is
+	// no webhook secret.
+	Organizations []Organization
+	// Policy decides on assignments based on the availability and working
+	// hours of deployers.
+	Policy   *policy.Policy
+	Provider provider.Provider
+	// ReconcileInterval is the interval the provider is reconciled with the
+	// registry in, if the registry is persistent.
+	ReconcileInterval time.Duration
+	// Registry keeps track of the assignments.
+	Registry *assignment.Registry
+	// TTL decides how long deployers stay on call.
+	TTL   *ttl.Rules
+	Users map[string]string
+	// WebhookSecret is the secret webhooks of organizations not configured
+	// are signed with. It may be read from a file and change while running.
+	WebhookSecret *secret.Secret
+	// WebhookSecrets are further secrets accepted for webhooks, tried in
+	// order after WebhookSecret, e.g. while rotating it.
+	WebhookSecrets []string
 }
 
 type Service struct {
+	audit      *audit.Service
 	httpClient *http.Client
 	logger     micrologger.Logger
 
-	githubToken   string
-	opsgenie      *opsgenie.OpsGenie
-	users         map[string]string
-	webhookSecret []byte
+	announceMode      string
+	announceTemplate  *template.Template
+	externalURL       string
+	githubToken       *secret.Secret
+	handover          string
+	notifier          *notifier.Service
+	organizations     map[string]Organization
+	policy            *policy.Policy
+	provider          provider.Provider
+	reconcileInterval time.Duration
+	registry          *assignment.Registry
+	ttl               *ttl.Rules
+	users             map[string]string
+	webhookSecret     *secret.Secret
+	webhookSecrets    []string
+
+	// mutex serializes changes of assignments.
+	mutex sync.Mutex
+	// queue holds webhooks waiting to be processed.
+	queue chan queued
 }
 
 func New(c Config) (*Service, error) {
-	if c.GithubToken == "" {
+	if c.AnnounceMode == "" {
+		c.AnnounceMode = AnnounceNone
+	}
+	if c.AnnounceMode != AnnounceNone && c.AnnounceMode != AnnounceComment && c.AnnounceMode != AnnounceStatus {
+		return nil, microerror.Maskf(invalidConfigError, "AnnounceMode must be %#q, %#q or %#q, got %#q", AnnounceNone, AnnounceComment, AnnounceStatus, c.AnnounceMode)
+	}
+	if c.Audit == nil {
+		return nil, microerror.Maskf(invalidConfigError, "Audit must not be empty")
+	}
+	if c.GithubToken.Value() == "" && len(c.Organizations) == 0 {
 		return nil, microerror.Maskf(invalidConfigError, "GithubToken must not be empty")
 	}
+	if c.Handover != HandoverKeep && c.Handover != HandoverReplace && c.Handover != HandoverShare {
+		return nil, microerror.Maskf(invalidConfigError, "Handover must be %#q, %#q or %#q, got %#q", HandoverKeep, HandoverReplace, HandoverShare, c.Handover)
+	}
 	if c.HttpClient == nil {
 		return nil, microerror.Maskf(invalidConfigError, "HttpClient must not be empty")
 	}
-	if c.Opsgenie == nil {
-		return nil, microerror.Maskf(invalidConfigError, "Opsgenie service must not be empty")
+	if c.Notifier == nil {
+		return nil, microerror.Maskf(invalidConfigError, "Notifier must not be empty")
+	}
+	if c.Policy == nil {
+		return nil, microerror.Maskf(invalidConfigError, "Policy must not be empty")
+	}
+	if c.Provider == nil {
+		return nil, microerror.Maskf(invalidConfigError, "Provider must not be empty")
+	}
+	if c.Registry == nil {
+		return nil, microerror.Maskf(invalidConfigError, "Registry must not be empty")
 	}
-	if c.WebhookSecret == "" {
+	if c.Registry.Persistent() && c.ReconcileInterval <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "ReconcileInterval must be positive")
+	}
+	if c.TTL == nil {
+		return nil, microerror.Maskf(invalidConfigError, "TTL must not be empty")
+	}
+	webhookSecrets, err := webhookSecrets(c.WebhookSecret.Value(), c.WebhookSecrets)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if len(webhookSecrets) == 0 && len(c.Organizations) == 0 {
 		return nil, microerror.Maskf(invalidConfigError, "Github organization webhook secret must not be empty")
 	}
 
-	service := &Service{
-		httpClient:    c.HttpClient,
-		githubToken:   c.GithubToken,
-		logger:        c.Logger,
-		opsgenie:      c.Opsgenie,
-		users:         c.Users,
-		webhookSecret: []byte(c.WebhookSecret),
+	organizations, err := validateOrganizations(c.Organizations, c.GithubToken, c.Users)
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
 
-	return service, nil
-}
-
-// Process performs processing of the webhook.
-func (s *Service) Process(h Hook) {
-	if !strings.HasPrefix(h.DeploymentEvent.Deployment.Environment, testEnvironmentPrefix) {
-		s.logger.Log("level", "debug", "message", "received deployment event", "repository", h.DeploymentEvent.Repository.Name, "ref", h.DeploymentEvent.Deployment.Ref, "environment", h.DeploymentEvent.Deployment.Environment)
-
-		err := s.createRoutingRule(h.DeploymentEvent)
-		if err != nil {
-			s.logger.Log("level", "error", "message", err.Error())
-		}
-	} else {
-		s.logger.Log("level", "debug", "message", "ignoring test environment", "repository", h.DeploymentEvent.Repository.Name, "ref", h.DeploymentEvent.Deployment.Ref, "environment", h.DeploymentEvent.Deployment.Environment)
+	announceTemplate, err := parseAnnounceTemplate(c.AnnounceTemplate)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
 
+	service := &Service{
+		announceMode:      c.AnnounceMode,
+		announceTemplate:  announceTemplate,
+		audit:             c.Audit,
+		externalURL:       strings.TrimSuffix(c.ExternalURL, "/"),
+		httpClient:        c.HttpClient,
+		githubToken:       c.GithubToken,
+		logger:            c.Logger,
+		handover:          c.Handover,
+		notifier:          c.Notifier,
+		organizations:     organizations,
+		policy:            c.Policy,
+		provider:          c.Provider,
+		reconcileInterval: c.ReconcileInterval,
+		registry:          c.Registry,
+		ttl:               c.TTL,
+		users:             c.Users,
+		webhookSecret:     c.WebhookSecret,
+		webhookSecrets:    c.WebhookSecrets,
+
+		queue: make(chan queued, queueSize),
 	}
+
+	return service, nil
 }
 
-func (s *Service) createRoutingRule(event DeploymentEvent) error {
-	var err error
-
-	conditions := []opsgenie.Rule{
-		opsgenie.Rule{
-			Value: event.Repository.Name,
-		},
-	}
-
-	ttl := time.Now().Add(routingRuleTTL).UTC().Unix()
-
-	// if deployment creator is bot account, then
-	// retrieve reference commit author, else
-	// use deployment creator
-	var githubLogin string
-	{
-		if event.Deployment.Creator.Login == botAccount {
-			// get commit from refference
-			req, err := http.NewRequest("GET", fmt.Sprintf(commitEndpoint, event.Repository.FullName, event.Deployment.Ref), nil)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-
-			req.Header.Set("Authorization", fmt.Sprintf("token %s", s.githubToken))
-
-			resp, err := s.httpClient.Do(req)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-
-			body, err := ioutil.ReadAll(resp.Body)
-			resp.Body.Close()
-
-			commit := Commit{}
-			err = json.Unmarshal(body, &commit)
-			if err != nil {
-				return microerror.Mask(err)
-			}
-			githubLogin = commit.Author.Login
-		} else {
-			githubLogin = event.Deployment.Creator.Login
-		}
+// Process performs processing of the webhook. It decides whom to put on call
+// for the deployment and creates the assignment. Processing is traced as
+// child of the span in the given context.
+func (s *Service) Process(ctx context.Context, h Hook) {
+	ctx, span := tracing.Start(ctx, "process")
+	span.SetAttribute("delivery", h.ID)
+	span.SetAttribute("event", h.Event)
+	span.SetAttribute("repository", h.DeploymentEvent.Repository.Name)
+	span.SetAttribute("environment", h.DeploymentEvent.Deployment.Environment)
+
+	d, err := s.Decide(ctx, h)
+	span.SetAttribute("skipped", d.Skipped)
+	if err != nil {
+		span.End(err)
+		hooksTotal.WithLabelValues(eventLabel(h.Event), outcomeFailed).Inc()
+		s.logger.Log("level", "error", "message", err.Error(), "delivery", h.ID)
+		return
 	}
 
-	user, ok := s.users[githubLogin]
-	if !ok {
-		return microerror.Maskf(userNotFoundError, githubLogin)
+	by := actor{name: h.DeploymentEvent.Deployment.Creator.Login, origin: audit.OriginWebhook}
+	for _, p := range d.Policy {
+		s.recordPolicy(by, *d.Assignment, p)
+		s.logger.Log("level", "info", "message", p.Reason, "policy", p.Action, "delivery", h.ID, "user", d.Assignment.User)
 	}
-
-	routingRule := &opsgenie.RoutingRule{
-		Name:       fmt.Sprintf("auto-%s-%s-%s-%s-%s", event.Repository.Name, event.Deployment.Ref, event.Deployment.Environment, githubLogin, strconv.FormatInt(ttl, 10)),
-		Cluster:    event.Deployment.Environment,
-		Conditions: conditions,
-		Type:       routingRuleType,
-		User:       user,
+	if d.Skipped {
+		span.End(nil)
+		hooksTotal.WithLabelValues(eventLabel(h.Event), outcomeSkipped).Inc()
+		s.logger.Log("level", "debug", "message", d.Reason, "delivery", h.ID, "repository", h.DeploymentEvent.Repository.Name, "ref", h.DeploymentEvent.Deployment.Ref, "environment", h.DeploymentEvent.Deployment.Environment)
+		return
 	}
 
-	err = s.opsgenie.CreateEscalation(routingRule)
+	a, err := s.assign(ctx, *d.Assignment, by)
+	span.End(err)
 	if err != nil {
-		return microerror.Mask(err)
+		hooksTotal.WithLabelValues(eventLabel(h.Event), outcomeFailed).Inc()
+		s.logger.Log("level", "error", "message", err.Error(), "delivery", h.ID)
+		return
 	}
-	s.logger.Log("level", "debug", "message", fmt.Sprintf("escalation %#q for user %#q has been created", routingRule.Name, routingRule.User))
 
-	err = s.opsgenie.CreateRoutingRule(routingRule)
-	if err != nil {
-		return microerror.Mask(err)
+	// With handover mode keep, the previous deployer may stay on call
+	// instead. When the assignment of the deployer is deferred rather than
+	// skipped, they are told when they go on call. Assignments of a schedule
+	// instead of the deployer are not announced.
+	if p, ok := s.registry.Get(d.Assignment.Name); ok && p.Pending() {
+		a = p
+	}
+	if _, ok := a.Schedule(); a.User == d.Assignment.User && !ok {
+		err = s.announce(ctx, h.DeploymentEvent, a)
+		if err != nil {
+			s.logger.Log("level", "error", "message", fmt.Sprintf("announcing assignment %#q failed", a.Name), "stack", fmt.Sprintf("%#v", err), "delivery", h.ID)
+		}
 	}
-	s.logger.Log("level", "debug", "message", fmt.Sprintf("routing rule %#q for user %#q has been created", routingRule.Name, routingRule.User))
 
-	return nil
+	hooksTotal.WithLabelValues(eventLabel(h.Event), outcomeAssigned).Inc()
 }
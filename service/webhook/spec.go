@@ -9,14 +9,31 @@ type Author struct {
 }
 
 type DeploymentEvent struct {
-	Deployment Deployment
-	Repository Repository `json:"repository"`
+	Deployment   Deployment
+	Organization Account    `json:"organization"`
+	Repository   Repository `json:"repository"`
+}
+
+// owner returns the login of the organization the event was sent by, or of
+// the owner of the repository for repository webhooks.
+func (e DeploymentEvent) owner() string {
+	if e.Organization.Login != "" {
+		return e.Organization.Login
+	}
+
+	return e.Repository.Owner.Login
+}
+
+type Account struct {
+	Login string `json:"login"`
 }
 
 type Deployment struct {
+	ID          int64 `json:"id"`
 	Creator     Creator
 	Environment string `json:"environment"`
 	Ref         string `json:"ref"`
+	SHA         string `json:"sha"`
 }
 
 type Creator struct {
@@ -24,6 +41,30 @@ type Creator struct {
 }
 
 type Repository struct {
-	FullName string `json:"full_name"`
-	Name     string `json:"name"`
+	FullName string  `json:"full_name"`
+	Name     string  `json:"name"`
+	Owner    Account `json:"owner"`
+}
+
+type DeploymentStatus struct {
+	State          string `json:"state"`
+	Description    string `json:"description,omitempty"`
+	EnvironmentURL string `json:"environment_url,omitempty"`
+	LogURL         string `json:"log_url,omitempty"`
+	// AutoInactive is only sent, never received. It is always false, so that
+	// reposting a status does not touch other deployments.
+	AutoInactive bool `json:"auto_inactive"`
+}
+
+type CommitComment struct {
+	Body string `json:"body"`
+}
+
+type Comparison struct {
+	TotalCommits int              `json:"total_commits"`
+	Files        []ComparisonFile `json:"files"`
+}
+
+type ComparisonFile struct {
+	Changes int `json:"changes"`
 }
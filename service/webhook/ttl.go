@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/auto-oncall/service/tracing"
+	"github.com/giantswarm/auto-oncall/service/ttl"
+)
+
+const (
+	compareEndpoint     = "https://api.github.com/repos/%s/compare/%s...%s"
+	deploymentsEndpoint = "https://api.github.com/repos/%s/deployments?environment=%s&per_page=10"
+)
+
+// decideTTL returns the TTL of the assignment of the given deployment. When
+// the TTL depends on the size of the change, the change since the previous
+// deployment to the environment is looked up on GitHub. The TTL of the rule
+// is used as is when that fails.
+func (s *Service) decideTTL(ctx context.Context, event DeploymentEvent) (time.Duration, ttl.Decision) {
+	ctx, span := tracing.Start(ctx, "ttl")
+	defer span.End(nil)
+
+	rule := s.ttl.Match(event.Repository.Name, event.Deployment.Environment)
+
+	var change *ttl.Change
+	if rule.Scaled() {
+		c, err := s.changeSize(ctx, event)
+		if err != nil {
+			s.logger.Log("level", "warning", "message", "looking up the size of the change failed, ignoring it for the TTL", "repository", event.Repository.Name, "environment", event.Deployment.Environment, "stack", fmt.Sprintf("%#v", err))
+		} else {
+			change = &c
+		}
+	}
+
+	d, decision := rule.Decide(change)
+	span.SetAttribute("ttl", decision.TTL)
+	span.SetAttribute("until_superseded", decision.UntilSuperseded)
+
+	return d, decision
+}
+
+// changeSize returns the number of commits and lines changed between the
+// previous deployment of the repository to the environment and the given
+// deployment. The change is empty for the first deployment.
+func (s *Service) changeSize(ctx context.Context, event DeploymentEvent) (ttl.Change, error) {
+	var deployments []Deployment
+	err := s.github(ctx, event.owner(), "GET", fmt.Sprintf(deploymentsEndpoint, event.Repository.FullName, url.QueryEscape(event.Deployment.Environment)), nil, &deployments)
+	if err != nil {
+		return ttl.Change{}, microerror.Mask(err)
+	}
+
+	// Deployments are listed newest first, the previous deployment is the
+	// first one created before the given deployment.
+	var base string
+	for _, d := range deployments {
+		if d.ID < event.Deployment.ID {
+			base = d.SHA
+			break
+		}
+	}
+
+	head := event.Deployment.SHA
+	if head == "" {
+		head = event.Deployment.Ref
+	}
+	if base == "" || base == head {
+		return ttl.Change{}, nil
+	}
+
+	var comparison Comparison
+	err = s.github(ctx, event.owner(), "GET", fmt.Sprintf(compareEndpoint, event.Repository.FullName, base, head), nil, &comparison)
+	if err != nil {
+		return ttl.Change{}, microerror.Mask(err)
+	}
+
+	c := ttl.Change{
+		Commits: comparison.TotalCommits,
+	}
+	for _, f := range comparison.Files {
+		c.Lines += f.Changes
+	}
+
+	return c, nil
+}